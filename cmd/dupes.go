@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"dtree/internal/color"
+	"dtree/internal/filter"
+	"dtree/internal/glob"
+	"dtree/internal/stats"
+	"dtree/internal/tree"
+)
+
+var (
+	dupesHidden  bool
+	dupesIgnores []string
+	dupesMinSize string
+	dupesHash    string
+	dupesJobs    int
+	dupesOneFS   bool
+	dupesNoColor bool
+	dupesTheme   string
+)
+
+var dupesCmd = &cobra.Command{
+	Use:   "dupes [path]",
+	Short: "Find duplicate files and directories",
+	Long: `dupes lists the files and directories under path that have identical
+contents, marking each copy with its group, and reports how much space
+removing the extra copies would free.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runDupes,
+}
+
+func init() {
+	dupesCmd.Flags().BoolVarP(&dupesHidden, "all", "a", false, "Include hidden files and directories")
+	dupesCmd.Flags().StringArrayVarP(&dupesIgnores, "ignore", "I", nil, "Skip files and directories matching the glob pattern (repeatable)")
+	dupesCmd.Flags().StringVar(&dupesMinSize, "min-size", "", "Ignore files smaller than this size (e.g. 1M)")
+	dupesCmd.Flags().StringVar(&dupesHash, "hash", "sha256", "Hash used to confirm duplicates: sha256, sha1, md5 or crc32")
+	dupesCmd.Flags().IntVarP(&dupesJobs, "jobs", "j", runtime.NumCPU(), "Number of files to read in parallel")
+	dupesCmd.Flags().BoolVarP(&dupesOneFS, "one-file-system", "x", false, "Stay on the filesystem of the root directory")
+	dupesCmd.Flags().BoolVar(&dupesNoColor, "no-color", false, "Disable color output")
+	dupesCmd.Flags().StringVar(&dupesTheme, "theme", "", "Color theme (see dtree --help)")
+	rootCmd.AddCommand(dupesCmd)
+}
+
+func runDupes(cmd *cobra.Command, args []string) error {
+	if err := tree.CheckHashAlgorithm(dupesHash); err != nil {
+		return err
+	}
+	var minSize int64
+	if dupesMinSize != "" {
+		var err error
+		minSize, err = filter.ParseSize(dupesMinSize)
+		if err != nil {
+			return fmt.Errorf("invalid --min-size: %w", err)
+		}
+	}
+	ignorePatterns, err := compilePatterns(dupesIgnores, glob.Options{Braces: true})
+	if err != nil {
+		return err
+	}
+
+	// Arguments are valid at this point; later errors are not usage errors
+	cmd.SilenceUsage = true
+
+	theme, err := newTheme(dupesTheme, !dupesNoColor && color.IsTTY())
+	if err != nil {
+		return err
+	}
+
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+
+	ctx, cancel := walkContext()
+	defer cancel()
+
+	options := tree.WalkerOptions{
+		ShowHidden:     dupesHidden,
+		RootPath:       absPath,
+		IgnorePatterns: ignorePatterns,
+		OneFileSystem:  dupesOneFS,
+		Jobs:           dupesJobs,
+	}
+	root, err := tree.WalkPath(ctx, absPath, options)
+	if err != nil {
+		return fmt.Errorf("failed to build tree: %w", err)
+	}
+
+	groups, err := tree.FindDuplicatesPath(ctx, absPath, root, tree.DupeOptions{
+		Algorithm: dupesHash,
+		MinSize:   minSize,
+		Jobs:      dupesJobs,
+	})
+	if err != nil {
+		return err
+	}
+	cancel()
+
+	if len(groups) == 0 {
+		fmt.Println("No duplicates found")
+	} else {
+		tree.KeepDuplicates(root)
+		if err := renderDupes(root, groups, theme); err != nil {
+			return err
+		}
+	}
+
+	if root.Incomplete {
+		return interruptedError(ctx)
+	}
+	return nil
+}
+
+// renderDupes prints the tree of duplicates followed by a summary of the
+// groups and the space they waste
+func renderDupes(root *tree.Node, groups []*tree.DupeGroup, theme *color.Theme) error {
+	renderer := tree.NewRenderer(os.Stdout)
+	if theme.IsEnabled() {
+		renderer.AddDecorator(tree.NewColorDecorator(theme))
+	}
+	if err := renderer.RenderTree(root, true); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	var total int64
+	for _, group := range groups {
+		kind := "files"
+		if group.IsDir {
+			kind = "directories"
+		}
+		reclaimable := group.Reclaimable()
+		total += reclaimable
+		fmt.Printf("#%d  %d %s of %s, %s reclaimable\n", group.ID, len(group.Nodes), kind,
+			stats.FormatSize(group.Size), stats.FormatSize(reclaimable))
+		for _, node := range group.Nodes {
+			fmt.Printf("    %s\n", node.RelPath())
+		}
+	}
+	fmt.Printf("\nTotal: %d duplicate groups, %s reclaimable\n", len(groups), stats.FormatSize(total))
+	return nil
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"dtree/internal/stats"
+	"dtree/internal/tree"
+)
+
+// progressInterval is how often the progress line is redrawn
+const progressInterval = 200 * time.Millisecond
+
+// stderrIsTTY checks if stderr is a terminal
+func stderrIsTTY() bool {
+	fileInfo, err := os.Stderr.Stat()
+	if err != nil {
+		return false
+	}
+	return (fileInfo.Mode() & os.ModeCharDevice) != 0
+}
+
+// startProgress redraws a one-line summary of progress on stderr until the
+// returned function is called, which clears the line again. The function
+// may be called more than once.
+func startProgress(progress *tree.Progress) func() {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		drawn := false
+		for {
+			select {
+			case <-done:
+				if drawn {
+					fmt.Fprint(os.Stderr, "\r\033[K")
+				}
+				return
+			case <-ticker.C:
+				fmt.Fprint(os.Stderr, "\r\033[K"+progressLine(progress))
+				drawn = true
+			}
+		}
+	}()
+
+	return sync.OnceFunc(func() {
+		close(done)
+		wg.Wait()
+	})
+}
+
+// progressLine formats the counters of progress and the current path. The
+// line is cut to the width of the terminal, since a line that wraps could
+// not be cleared; long paths keep their end.
+func progressLine(progress *tree.Progress) string {
+	var status string
+	if phase := progress.Phase(); phase != "" {
+		status = fmt.Sprintf("%s: %d files, %s", phase,
+			progress.PhaseFiles(), stats.FormatSize(progress.PhaseBytes()))
+	} else {
+		status = fmt.Sprintf("%d dirs, %d files, %s",
+			progress.Dirs(), progress.Files(), stats.FormatSize(progress.Bytes()))
+	}
+
+	width, _, err := term.GetSize(int(os.Stderr.Fd()))
+	if err != nil || width <= 0 {
+		return status + "  " + progress.Current()
+	}
+	// Leave the last column free so the cursor never wraps
+	width--
+
+	line := []rune(status)
+	if len(line) >= width {
+		return string(line[:max(width, 0)])
+	}
+	path := []rune(progress.Current())
+	room := width - len(line) - 2
+	if room < 4 {
+		return string(line)
+	}
+	if len(path) > room {
+		path = append([]rune("…"), path[len(path)-room+1:]...)
+	}
+	return string(line) + "  " + string(path)
+}
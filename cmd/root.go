@@ -1,14 +1,24 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"dtree/internal/color"
 	"dtree/internal/export"
+	"dtree/internal/filter"
+	"dtree/internal/glob"
+	"dtree/internal/icons"
 	"dtree/internal/tree"
 )
 
@@ -17,6 +27,7 @@ var (
 	showHidden  bool
 	maxDepth    int
 	noColor     bool
+	themeName   string
 	showSize    bool
 	showDate    bool
 	showLong    bool
@@ -25,6 +36,44 @@ var (
 	exportMD    bool
 	exportPlain bool
 	outputFile  string
+	gitIgnore   bool
+	patterns    []string
+	ignores     []string
+	ignoreCase  bool
+	prune       bool
+	follow      bool
+	noFollow    bool
+	jobs        int
+	strict      bool
+	oneFS       bool
+	stream      bool
+	archives    bool
+	fromFile    string
+	loadFile    string
+	diskUsage   bool
+	fileLimit   int
+	maxEntries  int
+	timeout     time.Duration
+	showPerms   bool
+	showOwner   bool
+	showGroup   bool
+	showInodes  bool
+	hashAlgo    string
+	countLOC    bool
+	hyperlinks  bool
+	charsetName string
+	indent      int
+	charset     tree.Charset
+	iconStyle   string
+	iconSet     *icons.Set
+	minSize     string
+	maxSize     string
+	newerThan   string
+	olderThan   string
+	fileType    string
+	detect      bool
+	onlyEmpty   bool
+	whereExpr   string
 )
 
 var rootCmd = &cobra.Command{
@@ -34,28 +83,126 @@ var rootCmd = &cobra.Command{
 the structure of directories in a tree format.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runTree,
+	// Execute reports errors itself
+	SilenceErrors: true,
 }
 
 func init() {
 	rootCmd.Flags().BoolVarP(&showHidden, "all", "a", false, "Show hidden files and directories")
 	rootCmd.Flags().IntVarP(&maxDepth, "depth", "d", 0, "Maximum depth to traverse (0 = unlimited)")
 	rootCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable color output")
+	rootCmd.Flags().StringVar(&themeName, "theme", "", "Color theme: default, dark, light, colorblind or one from ~/.config/dtree/themes.toml")
 	rootCmd.Flags().BoolVarP(&showSize, "size", "s", false, "Show file sizes")
 	rootCmd.Flags().BoolVarP(&showDate, "date", "t", false, "Show modification dates")
 	rootCmd.Flags().BoolVarP(&showLong, "long", "l", false, "Show detailed information (size and date)")
+	rootCmd.Flags().BoolVarP(&showPerms, "perms", "p", false, "Show permissions")
+	rootCmd.Flags().BoolVarP(&showOwner, "owner", "u", false, "Show the owning user")
+	rootCmd.Flags().BoolVarP(&showGroup, "group", "g", false, "Show the owning group")
+	rootCmd.Flags().BoolVar(&showInodes, "inodes", false, "Show inode numbers")
+	rootCmd.Flags().StringVar(&hashAlgo, "hash", "", "Show content hashes, with Merkle hashes for directories: sha256, sha1, md5 or crc32")
+	rootCmd.Flags().BoolVar(&countLOC, "loc", false, "Show line counts per file and directory, with a summary per language")
+	rootCmd.Flags().BoolVar(&hyperlinks, "hyperlinks", false, "Make names clickable links to the files, in terminals that support them")
+	rootCmd.Flags().StringVar(&charsetName, "charset", "unicode", "Characters for the tree lines: ascii, unicode, rounded, heavy or double")
+	rootCmd.Flags().StringVar(&iconStyle, "icons", "", "Show an icon before each name: nerd (needs a Nerd Font) or emoji")
+	rootCmd.Flags().IntVar(&indent, "indent", 4, "Number of columns each level is indented by")
 	rootCmd.Flags().StringVar(&sortBy, "sort", "", "Sort by: name, size, or date")
+	rootCmd.Flags().BoolVar(&diskUsage, "disk-usage", false, "Show space allocated on disk, counting hard links once (implies --size)")
 	rootCmd.Flags().BoolVar(&exportJSON, "json", false, "Export as JSON")
 	rootCmd.Flags().BoolVar(&exportMD, "md", false, "Export as Markdown")
 	rootCmd.Flags().BoolVar(&exportPlain, "plain", false, "Export as plain text (no box characters)")
 	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output to file")
+	rootCmd.Flags().BoolVar(&gitIgnore, "gitignore", false, "Hide files matched by .gitignore rules")
+	rootCmd.Flags().StringArrayVarP(&patterns, "pattern", "P", nil, "List only files matching the glob pattern (repeatable)")
+	rootCmd.Flags().StringArrayVarP(&ignores, "ignore", "I", nil, "Skip files and directories matching the glob pattern (repeatable)")
+	rootCmd.Flags().BoolVar(&ignoreCase, "ignore-case", false, "Match patterns case-insensitively")
+	rootCmd.Flags().BoolVar(&prune, "prune", false, "Remove directories left with no entries")
+	rootCmd.Flags().StringVar(&minSize, "min-size", "", "List only files of at least this size (e.g. 10M)")
+	rootCmd.Flags().StringVar(&maxSize, "max-size", "", "List only files of at most this size (e.g. 512K)")
+	rootCmd.Flags().StringVar(&newerThan, "newer", "", "List only entries modified after a date or within a duration (e.g. 7d)")
+	rootCmd.Flags().StringVar(&olderThan, "older", "", "List only entries modified before a date or longer ago than a duration")
+	rootCmd.Flags().StringVar(&fileType, "type", "", "List only entries of these types: f, d, l, p, s or a content kind such as script (comma-separated)")
+	rootCmd.Flags().BoolVar(&detect, "detect", false, "Detect file types from their contents (magic bytes, shebang lines, text or binary)")
+	rootCmd.Flags().BoolVar(&onlyEmpty, "empty", false, "List only empty files and directories")
+	rootCmd.Flags().StringVar(&whereExpr, "where", "", "List only entries matching an expression (e.g. 'size>10M && ext==\".log\"')")
+	rootCmd.Flags().StringVar(&fromFile, "fromfile", "", "Build the tree from a list of paths in FILE (- for stdin) instead of the disk")
+	rootCmd.Flags().StringVar(&loadFile, "load", "", "Load the tree from a snapshot written by --json instead of the disk")
+	rootCmd.Flags().BoolVar(&archives, "archives", false, "List the contents of zip, jar and tar archives")
+	rootCmd.Flags().BoolVar(&follow, "follow", false, "Descend into symlinked directories")
+	rootCmd.Flags().BoolVar(&noFollow, "no-follow", false, "Do not descend into symlinked directories (default)")
+	rootCmd.MarkFlagsMutuallyExclusive("follow", "no-follow")
+	rootCmd.Flags().BoolVarP(&oneFS, "one-file-system", "x", false, "Stay on the filesystem of the root directory")
+	rootCmd.Flags().BoolVar(&stream, "stream", false, "Print entries while walking, without keeping the whole tree in memory (tree, --plain and --json only)")
+	rootCmd.Flags().IntVar(&fileLimit, "filelimit", 0, "Do not descend into directories with more than N entries")
+	rootCmd.Flags().IntVar(&maxEntries, "max-entries", 0, "Stop after listing N entries in total")
+	rootCmd.Flags().DurationVar(&timeout, "timeout", 0, "Stop walking after this long and show what was found (e.g. 30s)")
+	rootCmd.Flags().BoolVar(&strict, "strict", false, "Exit with an error if any entry could not be read")
+	rootCmd.Flags().IntVarP(&jobs, "jobs", "j", runtime.NumCPU(), "Number of directories to read in parallel")
 }
 
 func runTree(cmd *cobra.Command, args []string) error {
+	if fromFile != "" && loadFile != "" {
+		return fmt.Errorf("--fromfile cannot be combined with --load")
+	}
+	for flag, value := range map[string]string{"--fromfile": fromFile, "--load": loadFile} {
+		if value == "" {
+			continue
+		}
+		if len(args) > 0 {
+			return fmt.Errorf("%s cannot be combined with a path argument", flag)
+		}
+		if stream {
+			return fmt.Errorf("--stream cannot be combined with %s", flag)
+		}
+		// Snapshots carry their hashes; path lists may name files anywhere
+		if hashAlgo != "" {
+			return fmt.Errorf("--hash cannot be combined with %s", flag)
+		}
+		if countLOC && flag == "--fromfile" {
+			return fmt.Errorf("--loc cannot be combined with %s", flag)
+		}
+		// These change how directories are read, and neither source reads
+		// any. Snapshots keep the kinds found by --detect for --type.
+		diskOnly := map[string]bool{
+			"--gitignore":        gitIgnore,
+			"--archives":         archives,
+			"--follow":           follow && !noFollow,
+			"--one-file-system":  oneFS,
+			"--detect":           detect,
+			"--type " + fileType: typeNeedsDetect(fileType) && flag == "--fromfile",
+			"--filelimit":        fileLimit > 0,
+			"--max-entries":      maxEntries > 0,
+		}
+		for option, set := range diskOnly {
+			if set {
+				return fmt.Errorf("%s cannot be combined with %s", option, flag)
+			}
+		}
+	}
+
+	if hashAlgo != "" {
+		if err := tree.CheckHashAlgorithm(hashAlgo); err != nil {
+			return err
+		}
+	}
+	var err error
+	charset, err = tree.NewCharset(charsetName, indent)
+	if err != nil {
+		return err
+	}
+	if iconStyle != "" {
+		iconSet, err = icons.New(iconStyle)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Arguments are valid at this point; later errors are not usage errors
+	cmd.SilenceUsage = true
+
 	// Determine root path
 	if len(args) > 0 {
 		rootPath = args[0]
 	} else {
-		var err error
 		rootPath, err = os.Getwd()
 		if err != nil {
 			return fmt.Errorf("failed to get current directory: %w", err)
@@ -73,16 +220,44 @@ func runTree(cmd *cobra.Command, args []string) error {
 		ShowHidden: showHidden,
 		MaxDepth:   maxDepth,
 		RootPath:   absPath,
+		Prune:      prune,
+
+		FollowSymlinks: follow && !noFollow,
+		OneFileSystem:  oneFS,
+		Archives:       archives,
+		Detect:         detect || typeNeedsDetect(fileType),
+		FileLimit:      fileLimit,
+		MaxEntries:     maxEntries,
+		Jobs:           jobs,
 	}
 
-	root, err := tree.WalkTree(absPath, options)
+	globOptions := glob.Options{IgnoreCase: ignoreCase, Braces: true}
+	options.Patterns, err = compilePatterns(patterns, globOptions)
 	if err != nil {
-		return fmt.Errorf("failed to build tree: %w", err)
+		return err
+	}
+	options.IgnorePatterns, err = compilePatterns(ignores, globOptions)
+	if err != nil {
+		return err
+	}
+
+	options.Filter, err = buildFilter()
+	if err != nil {
+		return err
+	}
+
+	if gitIgnore {
+		options.GitIgnore, err = tree.LoadGitIgnore(absPath)
+		if err != nil {
+			return fmt.Errorf("failed to load .gitignore rules: %w", err)
+		}
 	}
 
 	// Setup theme
-	themeEnabled := !noColor && color.IsTTY()
-	theme := color.NewTheme(themeEnabled)
+	theme, err := newTheme(themeName, !noColor && color.IsTTY())
+	if err != nil {
+		return err
+	}
 
 	// Determine output writer
 	var writer *os.File
@@ -99,6 +274,199 @@ func runTree(cmd *cobra.Command, args []string) error {
 		writer = os.Stdout
 	}
 
+	// Ctrl-C and --timeout stop the walk; the partial tree is still shown
+	ctx, cancel := walkContext()
+	defer cancel()
+
+	if stream {
+		return streamOutput(ctx, cancel, absPath, options, writer, theme)
+	}
+
+	// Show progress while walking the disk and reading the files
+	stopProgress := func() {}
+	if fromFile == "" && loadFile == "" && stderrIsTTY() {
+		options.Progress = &tree.Progress{}
+		stopProgress = startProgress(options.Progress)
+		defer stopProgress()
+	}
+
+	root, err := buildTree(ctx, absPath, options)
+	if err != nil {
+		return fmt.Errorf("failed to build tree: %w", err)
+	}
+	if hashAlgo != "" && !root.Incomplete {
+		if err := tree.HashPath(ctx, absPath, root, hashAlgo, jobs, options.Progress); err != nil {
+			return err
+		}
+	}
+	// Snapshots already carry their line counts
+	if countLOC && loadFile == "" && !root.Incomplete {
+		if err := tree.CountLinesPath(ctx, absPath, root, jobs, options.Progress); err != nil {
+			return err
+		}
+	}
+	cancel()
+	stopProgress()
+
+	if err := renderOutput(root, writer, theme); err != nil {
+		return err
+	}
+	reportTruncation(root)
+	if root.Incomplete {
+		return interruptedError(ctx)
+	}
+
+	// Let scripts notice incomplete trees
+	if strict {
+		if count := root.ErrorCount(); count > 0 {
+			return fmt.Errorf("%d entries could not be read", count)
+		}
+	}
+
+	return nil
+}
+
+// walkContext returns a context that ends on Ctrl-C or when --timeout
+// expires. Calling cancel restores the default Ctrl-C behavior.
+func walkContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// interruptedError explains why a walk ended early
+func interruptedError(ctx context.Context) error {
+	if errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+		return fmt.Errorf("walk timed out after %s; the tree is incomplete", timeout)
+	}
+	return fmt.Errorf("walk interrupted; the tree is incomplete")
+}
+
+// buildTree walks the root path, or reads the tree from --fromfile or --load
+func buildTree(ctx context.Context, absPath string, options tree.WalkerOptions) (*tree.Node, error) {
+	if loadFile != "" {
+		root, err := export.ImportJSONFile(loadFile)
+		if err != nil {
+			return nil, err
+		}
+		tree.ApplyOptions(root, options)
+		return root, nil
+	}
+
+	if fromFile == "" {
+		return tree.WalkPath(ctx, absPath, options)
+	}
+
+	if fromFile == "-" {
+		return tree.BuildFromPaths(os.Stdin, options)
+	}
+	file, err := os.Open(fromFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return tree.BuildFromPaths(file, options)
+}
+
+// streamOutput renders or exports the tree while it is being walked
+func streamOutput(ctx context.Context, cancel context.CancelFunc, absPath string, options tree.WalkerOptions, writer *os.File, theme *color.Theme) error {
+	// These need the complete tree before anything can be printed
+	conflicts := map[string]bool{
+		"--size":       showSize,
+		"--date":       showDate,
+		"--long":       showLong,
+		"--sort":       sortBy != "",
+		"--disk-usage": diskUsage,
+		"--perms":      showPerms,
+		"--owner":      showOwner,
+		"--group":      showGroup,
+		"--inodes":     showInodes,
+		"--hash":       hashAlgo != "",
+		"--loc":        countLOC,
+		"--md":         exportMD,
+		"--prune":      prune,
+		"filters":      options.Filter != nil,
+	}
+	for flag, set := range conflicts {
+		if set {
+			return fmt.Errorf("--stream cannot be combined with %s", flag)
+		}
+	}
+
+	buffered := bufio.NewWriter(writer)
+	var visitor tree.Visitor
+	switch {
+	case exportJSON:
+		visitor = export.NewJSONStream(buffered)
+	case exportPlain:
+		visitor = export.NewPlainStream(buffered)
+	default:
+		renderer := tree.NewStreamRenderer(buffered, nameDecorators(theme, absPath)...)
+		renderer.SetCharset(charset)
+		visitor = renderer
+	}
+
+	counter := &errorCounter{Visitor: visitor}
+	err := tree.StreamPath(ctx, absPath, options, counter)
+	cancel()
+	if flushErr := buffered.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		return err
+	}
+	if counter.root != nil {
+		reportTruncation(counter.root)
+		if counter.root.Incomplete {
+			return interruptedError(ctx)
+		}
+	}
+
+	if strict && counter.count > 0 {
+		return fmt.Errorf("%d entries could not be read", counter.count)
+	}
+	return nil
+}
+
+// errorCounter counts unreadable entries passing through a visitor
+type errorCounter struct {
+	tree.Visitor
+	count int
+	root  *tree.Node
+}
+
+func (c *errorCounter) Enter(node *tree.Node, depth int, isLast bool) error {
+	if depth == 0 {
+		c.root = node
+	}
+	return c.Visitor.Enter(node, depth, isLast)
+}
+
+// Leave counts node once its directory has been read, which may have
+// failed part way
+func (c *errorCounter) Leave(node *tree.Node, depth int) error {
+	if node.Err != nil {
+		c.count++
+	}
+	return c.Visitor.Leave(node, depth)
+}
+
+// reportTruncation tells the user when --max-entries cut the walk short.
+// It goes to stderr so that exports stay valid.
+func reportTruncation(root *tree.Node) {
+	if root.Truncated {
+		fmt.Fprintln(os.Stderr, "dtree: output truncated: the --max-entries limit was reached")
+	}
+}
+
+// renderOutput writes the tree in the requested export format or renderer
+func renderOutput(root *tree.Node, writer *os.File, theme *color.Theme) error {
 	// Export formats
 	if exportJSON {
 		if outputFile != "" {
@@ -109,9 +477,9 @@ func runTree(cmd *cobra.Command, args []string) error {
 
 	if exportMD {
 		if outputFile != "" {
-			return export.ExportToMarkdownFile(root, outputFile)
+			return export.ExportToMarkdownFile(root, outputFile, export.MarkdownOptions{Charset: charset, Icons: iconSet})
 		}
-		return export.ExportToMarkdown(root, writer)
+		return export.ExportToMarkdown(root, writer, export.MarkdownOptions{Charset: charset, Icons: iconSet})
 	}
 
 	if exportPlain {
@@ -119,21 +487,159 @@ func runTree(cmd *cobra.Command, args []string) error {
 	}
 
 	// Render tree
-	if showSize || showDate || showLong || sortBy != "" {
-		// Use stats renderer
-		renderer := tree.NewRendererStats(writer, showSize, showDate, showLong, sortBy, true)
-		return renderer.RenderTreeWithStats(root, true)
+	renderer := tree.NewRenderer(writer)
+	renderer.SetCharset(charset)
+	for _, decorator := range nameDecorators(theme, root.GetFullPath()) {
+		renderer.AddDecorator(decorator)
+	}
+	columns := metadataColumns()
+	for _, column := range columns {
+		renderer.AddColumn(column)
+	}
+	renderer.SetSort(sortBy)
+	renderer.SetSummary(len(columns) > 0 || sortBy != "", diskUsage)
+	renderer.SetLanguageSummary(countLOC)
+	return renderer.RenderTree(root, true)
+}
+
+// metadataColumns returns the columns requested on the command line, in
+// display order
+func metadataColumns() []tree.Column {
+	var columns []tree.Column
+	if showInodes {
+		columns = append(columns, tree.NewInodeColumn())
+	}
+	if showPerms {
+		columns = append(columns, tree.NewPermissionsColumn())
+	}
+	if showOwner {
+		columns = append(columns, tree.NewOwnerColumn())
 	}
+	if showGroup {
+		columns = append(columns, tree.NewGroupColumn())
+	}
+	if showSize || showLong || diskUsage {
+		columns = append(columns, tree.NewSizeColumn(diskUsage, showLong))
+	}
+	if countLOC {
+		columns = append(columns, tree.NewLinesColumn())
+	}
+	if showDate || showLong {
+		columns = append(columns, tree.NewDateColumn(showLong))
+	}
+	if hashAlgo != "" {
+		columns = append(columns, tree.NewHashColumn())
+	}
+	return columns
+}
+
+// newTheme returns the named color theme, or the default one colored by
+// LS_COLORS when name is empty. Colors stay off where the environment
+// asks for that or the terminal cannot show them.
+func newTheme(name string, enabled bool) (*color.Theme, error) {
+	enabled = enabled && color.Supported()
+	if name == "" {
+		return color.NewTheme(enabled), nil
+	}
+	return color.LoadTheme(name, enabled)
+}
 
-	// Use color renderer if colors enabled
+// nameDecorators returns the decorators for names of a tree walked from
+// absPath. Colors come first, as they pick the color from the plain name.
+func nameDecorators(theme *color.Theme, absPath string) []tree.Decorator {
+	var decorators []tree.Decorator
 	if theme.IsEnabled() {
-		renderer := tree.NewRendererColor(writer, theme)
-		return renderer.RenderTree(root, true)
+		decorators = append(decorators, tree.NewColorDecorator(theme))
 	}
+	if iconSet != nil {
+		decorators = append(decorators, tree.NewIconDecorator(iconSet))
+	}
+	// Links point at files on disk, which snapshots and path lists may not have
+	if hyperlinks && outputFile == "" && loadFile == "" && fromFile == "" {
+		decorators = append(decorators, tree.NewLinkDecorator(absPath))
+	}
+	return decorators
+}
 
-	// Use basic renderer
-	renderer := tree.NewRenderer(writer)
-	return renderer.RenderTree(root, true)
+// buildFilter combines the find-style flags into a single predicate.
+// It returns nil when no filter was requested.
+func buildFilter() (func(*tree.Node) bool, error) {
+	var preds []filter.Predicate
+	now := time.Now()
+
+	if minSize != "" {
+		size, err := filter.ParseSize(minSize)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, filter.MinSize(size))
+	}
+	if maxSize != "" {
+		size, err := filter.ParseSize(maxSize)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, filter.MaxSize(size))
+	}
+	if newerThan != "" {
+		t, err := filter.ParseTime(newerThan, now)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, filter.Newer(t))
+	}
+	if olderThan != "" {
+		t, err := filter.ParseTime(olderThan, now)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, filter.Older(t))
+	}
+	if fileType != "" {
+		pred, err := filter.Type(fileType)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, pred)
+	}
+	if onlyEmpty {
+		preds = append(preds, filter.Empty())
+	}
+	if whereExpr != "" {
+		pred, err := filter.Parse(whereExpr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --where expression: %w", err)
+		}
+		preds = append(preds, pred)
+	}
+
+	if len(preds) == 0 {
+		return nil, nil
+	}
+	return filter.And(preds...), nil
+}
+
+// typeNeedsDetect reports whether a --type list names a content kind,
+// which is only known when file contents are sniffed
+func typeNeedsDetect(types string) bool {
+	for _, kind := range strings.Split(types, ",") {
+		if filter.IsKind(strings.TrimSpace(kind)) {
+			return true
+		}
+	}
+	return false
+}
+
+func compilePatterns(patterns []string, opts glob.Options) ([]*glob.Pattern, error) {
+	compiled := make([]*glob.Pattern, 0, len(patterns))
+	for _, p := range patterns {
+		pattern, err := glob.CompileWith(p, opts)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, pattern)
+	}
+	return compiled, nil
 }
 
 // Execute runs the root command
@@ -143,4 +649,3 @@ func Execute() {
 		os.Exit(1)
 	}
 }
-
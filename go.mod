@@ -3,14 +3,13 @@ module dtree
 go 1.24.9
 
 require (
-	github.com/fatih/color v1.18.0
+	github.com/BurntSushi/toml v1.5.0
 	github.com/spf13/cobra v1.10.2
+	golang.org/x/sys v0.36.0
+	golang.org/x/term v0.35.0
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
-	golang.org/x/sys v0.36.0 // indirect
 )
@@ -0,0 +1,128 @@
+package color
+
+import (
+	"os"
+	"strings"
+)
+
+// LSColors holds the rules of an LS_COLORS value, as written by dircolors
+type LSColors struct {
+	types    map[string]string // type codes such as "di" to SGR sequences
+	suffixes []suffixRule
+}
+
+type suffixRule struct {
+	suffix string // lower case, e.g. ".tar.gz"
+	style  string
+}
+
+// LoadLSColors parses the LS_COLORS environment variable. It returns nil
+// when the variable is not set.
+func LoadLSColors() *LSColors {
+	value := os.Getenv("LS_COLORS")
+	if value == "" {
+		return nil
+	}
+	return ParseLSColors(value)
+}
+
+// ParseLSColors parses a colon-separated list of rules such as
+// "di=01;34:ln=01;36:*.md=00;33". Malformed rules are skipped, as ls does.
+func ParseLSColors(value string) *LSColors {
+	l := &LSColors{types: make(map[string]string)}
+	for _, rule := range strings.Split(value, ":") {
+		key, style, ok := strings.Cut(rule, "=")
+		if !ok || key == "" {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(key, "*"); ok {
+			l.suffixes = append(l.suffixes, suffixRule{suffix: strings.ToLower(suffix), style: style})
+		} else {
+			l.types[key] = style
+		}
+	}
+	return l
+}
+
+// Style returns the SGR sequence for an entry, following the precedence
+// of ls: special types and permission bits first, then the name suffix.
+// It reports false when no rule applies.
+func (l *LSColors) Style(entry Entry) (string, bool) {
+	mode := entry.Mode
+	switch {
+	case entry.IsSymlink:
+		if entry.IsOrphan {
+			if style, ok := l.types["or"]; ok {
+				return style, true
+			}
+		}
+		// "target" asks for the color of the target, which we do not have
+		if style, ok := l.types["ln"]; ok && style != "target" {
+			return style, true
+		}
+		return "", false
+	case entry.IsDir:
+		sticky, writable := mode&os.ModeSticky != 0, mode&0002 != 0
+		switch {
+		case sticky && writable:
+			return l.lookup("tw", "ow", "st", "di")
+		case writable:
+			return l.lookup("ow", "di")
+		case sticky:
+			return l.lookup("st", "di")
+		default:
+			return l.lookup("di")
+		}
+	case mode&os.ModeNamedPipe != 0:
+		return l.lookup("pi")
+	case mode&os.ModeSocket != 0:
+		return l.lookup("so")
+	case mode&os.ModeCharDevice != 0:
+		return l.lookup("cd")
+	case mode&os.ModeDevice != 0:
+		return l.lookup("bd")
+	}
+
+	if mode&os.ModeSetuid != 0 {
+		if style, ok := l.lookup("su"); ok {
+			return style, true
+		}
+	}
+	if mode&os.ModeSetgid != 0 {
+		if style, ok := l.lookup("sg"); ok {
+			return style, true
+		}
+	}
+	if mode&0111 != 0 {
+		if style, ok := l.lookup("ex"); ok {
+			return style, true
+		}
+	}
+	if style, ok := l.Suffix(entry.Name); ok {
+		return style, true
+	}
+	return l.lookup("fi")
+}
+
+// Suffix returns the SGR sequence of the "*suffix" rule matching name.
+// Suffixes match case-insensitively, and later rules override earlier ones.
+func (l *LSColors) Suffix(name string) (string, bool) {
+	name = strings.ToLower(name)
+	for i := len(l.suffixes) - 1; i >= 0; i-- {
+		if strings.HasSuffix(name, l.suffixes[i].suffix) {
+			return l.suffixes[i].style, true
+		}
+	}
+	return "", false
+}
+
+// lookup returns the style of the first type code that is set. Codes set
+// to an empty or zero style count as set, to turn a color off.
+func (l *LSColors) lookup(codes ...string) (string, bool) {
+	for _, code := range codes {
+		if style, ok := l.types[code]; ok {
+			return style, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,88 @@
+package color
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// styleSpec describes how a name is displayed. Colors are one of the 16
+// terminal color names ("red", "bright-red"), a 256-color palette index
+// ("208") or a truecolor hex value ("#ff8700").
+type styleSpec struct {
+	FG        string `toml:"fg"`
+	BG        string `toml:"bg"`
+	Bold      bool   `toml:"bold"`
+	Italic    bool   `toml:"italic"`
+	Underline bool   `toml:"underline"`
+}
+
+var colorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// sgr converts a style to the parameters of an SGR escape sequence, e.g.
+// "1;34" for bold blue
+func (s styleSpec) sgr() (string, error) {
+	var params []string
+	if s.Bold {
+		params = append(params, "1")
+	}
+	if s.Italic {
+		params = append(params, "3")
+	}
+	if s.Underline {
+		params = append(params, "4")
+	}
+	for _, c := range []struct {
+		value string
+		base  int // 30 for foreground, 40 for background
+	}{{s.FG, 30}, {s.BG, 40}} {
+		if c.value == "" {
+			continue
+		}
+		param, err := colorParam(c.value, c.base)
+		if err != nil {
+			return "", err
+		}
+		params = append(params, param)
+	}
+	return strings.Join(params, ";"), nil
+}
+
+// colorParam converts a color to SGR parameters, relative to base
+func colorParam(value string, base int) (string, error) {
+	name := strings.ToLower(value)
+	bright := 0
+	if rest, ok := strings.CutPrefix(name, "bright-"); ok {
+		name, bright = rest, 60
+	}
+	for i, known := range colorNames {
+		if name == known {
+			return strconv.Itoa(base + bright + i), nil
+		}
+	}
+
+	if hex, ok := strings.CutPrefix(value, "#"); ok {
+		rgb, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 6 {
+			return "", fmt.Errorf("invalid color %q (use #rrggbb)", value)
+		}
+		return fmt.Sprintf("%d;2;%d;%d;%d", base+8, rgb>>16, rgb>>8&0xff, rgb&0xff), nil
+	}
+
+	if index, err := strconv.Atoi(value); err == nil {
+		if index < 0 || index > 255 {
+			return "", fmt.Errorf("invalid color %q (palette indexes are 0-255)", value)
+		}
+		return fmt.Sprintf("%d;5;%d", base+8, index), nil
+	}
+
+	return "", fmt.Errorf("unknown color %q (use a name such as red or bright-red, 0-255 or #rrggbb)", value)
+}
+
+// paint wraps name in an SGR sequence
+func paint(style, name string) string {
+	if style == "" || style == "0" || style == "00" {
+		return name
+	}
+	return "\033[" + style + "m" + name + "\033[0m"
+}
@@ -4,30 +4,25 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-
-	"github.com/fatih/color"
-)
-
-var (
-	// Color functions
-	DirColor      = color.New(color.FgBlue, color.Bold)
-	ExecColor     = color.New(color.FgGreen)
-	SymlinkColor  = color.New(color.FgCyan)
-	ImageColor    = color.New(color.FgMagenta)
-	ArchiveColor  = color.New(color.FgYellow)
-	CodeColor     = color.New(color.FgCyan)
-	DocColor      = color.New(color.FgYellow)
-	DefaultColor  = color.New(color.Reset)
 )
 
 // Theme manages color output
 type Theme struct {
-	enabled bool
+	enabled    bool
+	categories map[Category]string // SGR parameters per category
+	extensions map[string]string   // SGR parameters per lower-case extension
+	globs      []globStyle         // tried in order before anything else
+	lsColors   *LSColors           // rules from LS_COLORS, or nil when not used
 }
 
-// NewTheme creates a new theme with color enabled/disabled
+// NewTheme creates the default theme with color enabled/disabled. Rules
+// from the LS_COLORS environment variable take precedence over its
+// colors.
 func NewTheme(enabled bool) *Theme {
-	return &Theme{enabled: enabled}
+	t := builtinTheme("default")
+	t.enabled = enabled
+	t.lsColors = LoadLSColors()
+	return t
 }
 
 // IsEnabled returns whether colors are enabled
@@ -35,52 +30,156 @@ func (t *Theme) IsEnabled() bool {
 	return t.enabled
 }
 
+// Entry describes a file to be colored
+type Entry struct {
+	Name      string
+	IsDir     bool
+	IsSymlink bool
+	Mode      os.FileMode
+	Kind      string // content kind from --detect, or "" if not sniffed
+	IsOrphan  bool   // symlink whose target does not exist
+}
+
 // Colorize applies appropriate color to a filename based on its type
 func (t *Theme) Colorize(name string, isDir bool, isSymlink bool, mode os.FileMode) string {
-	if !t.enabled {
-		return name
-	}
+	return t.ColorizeEntry(Entry{Name: name, IsDir: isDir, IsSymlink: isSymlink, Mode: mode})
+}
+
+// Category is the kind of file that decides how a name is displayed
+type Category int
 
-	if isSymlink {
-		return SymlinkColor.Sprint(name)
+const (
+	CategoryFile Category = iota
+	CategoryDir
+	CategorySymlink
+	CategoryExec
+	CategoryImage
+	CategoryArchive
+	CategoryCode
+	CategoryDoc
+)
+
+// Classify returns the category of an entry, from its type, its
+// permissions, its sniffed content kind and finally its extension
+func Classify(entry Entry) Category {
+	if entry.IsSymlink {
+		return CategorySymlink
 	}
 
-	if isDir {
-		return DirColor.Sprint(name)
+	if entry.IsDir {
+		return CategoryDir
 	}
 
 	// Check if executable
-	if mode&0111 != 0 {
-		return ExecColor.Sprint(name)
+	if entry.Mode&0111 != 0 {
+		return CategoryExec
 	}
 
-	// Color by extension
-	ext := strings.ToLower(filepath.Ext(name))
-	
+	// Classify by content
+	switch entry.Kind {
+	case "executable", "script":
+		return CategoryExec
+	case "image":
+		return CategoryImage
+	case "archive":
+		return CategoryArchive
+	case "code":
+		return CategoryCode
+	case "document":
+		return CategoryDoc
+	}
+
+	// Classify by extension
+	ext := strings.ToLower(filepath.Ext(entry.Name))
+
 	switch ext {
 	case ".jpg", ".jpeg", ".png", ".gif", ".bmp", ".svg", ".webp", ".ico":
-		return ImageColor.Sprint(name)
-	case ".zip", ".tar", ".gz", ".bz2", ".xz", ".rar", ".7z":
-		return ArchiveColor.Sprint(name)
+		return CategoryImage
+	case ".zip", ".tar", ".gz", ".bz2", ".xz", ".rar", ".7z", ".jar", ".tgz", ".tbz2":
+		return CategoryArchive
 	case ".go", ".js", ".ts", ".py", ".java", ".cpp", ".c", ".h", ".rs", ".rb", ".php", ".swift", ".kt":
-		return CodeColor.Sprint(name)
+		return CategoryCode
 	case ".md", ".txt", ".doc", ".docx", ".pdf", ".rtf":
-		return DocColor.Sprint(name)
+		return CategoryDoc
+	default:
+		return CategoryFile
+	}
+}
+
+// ColorizeEntry applies appropriate color to a filename based on its
+// category. A sniffed content kind takes precedence over the extension.
+// With LS_COLORS set, files are colored like ls colors them: its rules
+// come first, and only directories, symlinks, executables and files with
+// a sniffed content kind fall back to the theme.
+func (t *Theme) ColorizeEntry(entry Entry) string {
+	name := entry.Name
+	if !t.enabled {
+		return name
+	}
+
+	if t.lsColors != nil {
+		if style, ok := t.lsColors.Style(entry); ok {
+			return paint(style, name)
+		}
+		// Names are left to the suffix rules of LS_COLORS
+		if !entry.IsDir && !entry.IsSymlink && entry.Mode&0111 == 0 && entry.Kind == "" {
+			return name
+		}
+	}
+	return paint(t.style(entry), name)
+}
+
+// style returns the SGR parameters for an entry: those of the first
+// matching glob, then those of its extension unless it is a directory,
+// symlink or executable, and finally those of its category
+func (t *Theme) style(entry Entry) string {
+	for _, g := range t.globs {
+		if g.pattern.Match(entry.Name) {
+			return g.style
+		}
+	}
+
+	category := Classify(entry)
+	switch category {
+	case CategoryDir, CategorySymlink, CategoryExec:
 	default:
+		if style, ok := t.extensions[strings.ToLower(filepath.Ext(entry.Name))]; ok {
+			return style
+		}
+	}
+	return t.categories[category]
+}
+
+// ColorizeArchive applies the archive color to a name, for archives whose
+// contents are being listed
+func (t *Theme) ColorizeArchive(name string) string {
+	if !t.enabled {
 		return name
 	}
+	if t.lsColors != nil {
+		if style, ok := t.lsColors.Suffix(name); ok {
+			return paint(style, name)
+		}
+	}
+	for _, g := range t.globs {
+		if g.pattern.Match(name) {
+			return paint(g.style, name)
+		}
+	}
+	if style, ok := t.extensions[strings.ToLower(filepath.Ext(name))]; ok {
+		return paint(style, name)
+	}
+	return paint(t.categories[CategoryArchive], name)
 }
 
 // DisableColors disables color output
 func (t *Theme) DisableColors() {
 	t.enabled = false
-	color.NoColor = true
 }
 
 // EnableColors enables color output
 func (t *Theme) EnableColors() {
 	t.enabled = true
-	color.NoColor = false
 }
 
 // IsTTY checks if stdout is a terminal
@@ -92,3 +191,12 @@ func IsTTY() bool {
 	return (fileInfo.Mode() & os.ModeCharDevice) != 0
 }
 
+// Supported reports whether colors may be written to stdout: NO_COLOR is
+// not set, TERM is not "dumb", and on Windows the console accepts escape
+// sequences
+func Supported() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return enableVirtualTerminal(os.Stdout)
+}
@@ -0,0 +1,190 @@
+package color
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"dtree/internal/glob"
+)
+
+//go:embed themes.toml
+var builtinThemes string
+
+// themeSpec is a named theme as written in a themes file
+type themeSpec struct {
+	Inherit    string               `toml:"inherit"`
+	Categories map[string]styleSpec `toml:"categories"`
+	Extensions map[string]styleSpec `toml:"extensions"`
+	Globs      []globSpec           `toml:"globs"`
+}
+
+// globSpec styles the names matching a glob pattern
+type globSpec struct {
+	Pattern string `toml:"pattern"`
+	styleSpec
+}
+
+type globStyle struct {
+	pattern *glob.Pattern
+	style   string
+}
+
+var categoryNames = map[string]Category{
+	"file":    CategoryFile,
+	"dir":     CategoryDir,
+	"symlink": CategorySymlink,
+	"exec":    CategoryExec,
+	"image":   CategoryImage,
+	"archive": CategoryArchive,
+	"code":    CategoryCode,
+	"doc":     CategoryDoc,
+}
+
+// LoadTheme creates the theme called name, defined in the user's themes
+// file or built in. Unlike NewTheme, it does not use LS_COLORS.
+func LoadTheme(name string, enabled bool) (*Theme, error) {
+	themes, err := readThemes()
+	if err != nil {
+		return nil, err
+	}
+	t := &Theme{enabled: enabled, categories: make(map[Category]string), extensions: make(map[string]string)}
+	if err := t.apply(themes, name, nil); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// builtinTheme creates one of the built-in themes, which are known to
+// be valid
+func builtinTheme(name string) *Theme {
+	themes, err := decodeThemes(builtinThemes, "built-in themes")
+	if err != nil {
+		panic(err)
+	}
+	t := &Theme{categories: make(map[Category]string), extensions: make(map[string]string)}
+	if err := t.apply(themes, name, nil); err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// ThemesPath returns the location of the user's themes file,
+// $XDG_CONFIG_HOME/dtree/themes.toml or ~/.config/dtree/themes.toml
+func ThemesPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "dtree", "themes.toml"), nil
+}
+
+// readThemes returns the built-in themes, along with those of the user's
+// themes file if there is one. User themes replace built-in themes of the
+// same name.
+func readThemes() (map[string]themeSpec, error) {
+	themes, err := decodeThemes(builtinThemes, "built-in themes")
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := ThemesPath()
+	if err != nil {
+		return themes, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return themes, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read themes: %w", err)
+	}
+	user, err := decodeThemes(string(data), path)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(themes, user)
+	return themes, nil
+}
+
+// decodeThemes parses a themes file; source names it in errors
+func decodeThemes(data, source string) (map[string]themeSpec, error) {
+	var file struct {
+		Themes map[string]themeSpec `toml:"themes"`
+	}
+	meta, err := toml.Decode(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	// Catch misspelled keys, which would otherwise be silently ignored
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: unknown key %q", source, undecoded[0].String())
+	}
+	return file.Themes, nil
+}
+
+// apply adds the styles of the named theme to t, over those of the theme
+// it inherits from. seen lists the themes inheriting from this one.
+func (t *Theme) apply(themes map[string]themeSpec, name string, seen []string) error {
+	if slices.Contains(seen, name) {
+		return fmt.Errorf("theme %q inherits from itself", name)
+	}
+	spec, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q (use %s)", name, strings.Join(slices.Sorted(maps.Keys(themes)), ", "))
+	}
+	if spec.Inherit != "" {
+		if err := t.apply(themes, spec.Inherit, append(seen, name)); err != nil {
+			return err
+		}
+	}
+
+	for key, s := range spec.Categories {
+		category, ok := categoryNames[key]
+		if !ok {
+			return fmt.Errorf("theme %q: unknown category %q (use %s)", name, key,
+				strings.Join(slices.Sorted(maps.Keys(categoryNames)), ", "))
+		}
+		style, err := s.sgr()
+		if err != nil {
+			return fmt.Errorf("theme %q: %s: %w", name, key, err)
+		}
+		t.categories[category] = style
+	}
+
+	for ext, s := range spec.Extensions {
+		style, err := s.sgr()
+		if err != nil {
+			return fmt.Errorf("theme %q: %s: %w", name, ext, err)
+		}
+		t.extensions["."+strings.TrimPrefix(strings.ToLower(ext), ".")] = style
+	}
+
+	// Globs of the inheriting theme are tried first
+	globs := make([]globStyle, 0, len(spec.Globs)+len(t.globs))
+	for _, g := range spec.Globs {
+		pattern, err := glob.CompileWith(g.Pattern, glob.Options{Braces: true})
+		if err != nil {
+			return fmt.Errorf("theme %q: %w", name, err)
+		}
+		style, err := g.sgr()
+		if err != nil {
+			return fmt.Errorf("theme %q: %s: %w", name, g.Pattern, err)
+		}
+		globs = append(globs, globStyle{pattern: pattern, style: style})
+	}
+	t.globs = append(globs, t.globs...)
+	return nil
+}
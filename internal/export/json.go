@@ -12,12 +12,39 @@ import (
 
 // JSONNode represents a node in JSON format
 type JSONNode struct {
-	Name      string      `json:"name"`
-	Path      string      `json:"path"`
-	Type      string      `json:"type"`
-	Size      int64       `json:"size,omitempty"`
-	ModTime   string      `json:"modTime,omitempty"`
-	Children  []*JSONNode `json:"children,omitempty"`
+	Name        string      `json:"name"`
+	Path        string      `json:"path"`
+	Type        string      `json:"type"`
+	Size        int64       `json:"size,omitempty"`
+	DiskSize    int64       `json:"diskSize,omitempty"`
+	ModTime     string      `json:"modTime,omitempty"`
+	Permissions string      `json:"permissions,omitempty"`
+	Owner       string      `json:"owner,omitempty"`
+	Group       string      `json:"group,omitempty"`
+	UID         uint32      `json:"uid,omitempty"`
+	GID         uint32      `json:"gid,omitempty"`
+	Inode       uint64      `json:"inode,omitempty"`
+	Links       uint64      `json:"links,omitempty"`
+	Device      uint64      `json:"device,omitempty"`
+	Hash        string      `json:"hash,omitempty"`
+	MIME        string      `json:"mime,omitempty"`
+	Kind        string      `json:"kind,omitempty"`
+	Language    string      `json:"language,omitempty"`
+	Lines       int64       `json:"lines,omitempty"`
+	CodeLines   int64       `json:"codeLines,omitempty"`
+	Comments    int64       `json:"commentLines,omitempty"`
+	BlankLines  int64       `json:"blankLines,omitempty"`
+	LinkToDir   bool        `json:"linkToDir,omitempty"`
+	Broken      bool        `json:"broken,omitempty"`
+	Recursive   bool        `json:"recursive,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	MountPoint  bool        `json:"mountPoint,omitempty"`
+	DepthLimit  bool        `json:"depthLimited,omitempty"`
+	Entries     int         `json:"entries,omitempty"`
+	EntriesSize int64       `json:"entriesSize,omitempty"`
+	Truncated   bool        `json:"truncated,omitempty"`
+	Incomplete  bool        `json:"incomplete,omitempty"`
+	Children    []*JSONNode `json:"children,omitempty"`
 }
 
 // ExportToJSON exports the tree to JSON format
@@ -29,26 +56,69 @@ func ExportToJSON(root *tree.Node, writer io.Writer) error {
 }
 
 func nodeToJSON(node *tree.Node) *JSONNode {
+	jsonNode := newJSONNode(node)
+
+	if len(node.Children) > 0 {
+		jsonNode.Children = make([]*JSONNode, 0, len(node.Children))
+		for _, child := range node.Children {
+			jsonNode.Children = append(jsonNode.Children, nodeToJSON(child))
+		}
+	}
+
+	return jsonNode
+}
+
+// newJSONNode converts a single node, without its children
+func newJSONNode(node *tree.Node) *JSONNode {
 	jsonNode := &JSONNode{
-		Name: node.Name,
-		Path: node.Path,
+		Name:        node.Name,
+		Path:        node.Path,
+		Size:        node.Size,
+		DiskSize:    node.DiskSize,
+		Owner:       node.OwnerName(),
+		Group:       node.GroupName(),
+		UID:         node.UID,
+		GID:         node.GID,
+		Inode:       node.Inode,
+		Links:       node.Links,
+		Device:      node.Device,
+		Hash:        node.Hash,
+		MIME:        node.MIME,
+		Kind:        node.Kind,
+		Language:    node.Language,
+		Lines:       node.Lines.Total,
+		CodeLines:   node.Lines.Code(),
+		Comments:    node.Lines.Comment,
+		BlankLines:  node.Lines.Blank,
+		LinkToDir:   node.IsSymlink && node.IsDir,
+		Broken:      node.IsBroken,
+		Recursive:   node.IsLoop,
+		MountPoint:  node.IsMountPoint,
+		DepthLimit:  node.DepthLimited,
+		Entries:     node.EntryCount,
+		EntriesSize: node.EntryBytes,
+		Truncated:   node.Truncated,
+		Incomplete:  node.Incomplete,
+	}
+
+	if node.Err != nil {
+		jsonNode.Error = node.Err.Error()
+	}
+	if node.Mode != 0 {
+		jsonNode.Permissions = node.Permissions()
+	}
+	if !node.ModTime.IsZero() {
+		jsonNode.ModTime = node.ModTime.Format(time.RFC3339)
 	}
 
 	if node.IsSymlink {
 		jsonNode.Type = "symlink"
+	} else if node.IsArchive {
+		jsonNode.Type = "archive"
 	} else if node.IsDir {
 		jsonNode.Type = "directory"
 	} else {
 		jsonNode.Type = "file"
-		jsonNode.Size = node.Size
-		jsonNode.ModTime = node.ModTime.Format(time.RFC3339)
-	}
-
-	if len(node.Children) > 0 {
-		jsonNode.Children = make([]*JSONNode, 0, len(node.Children))
-		for _, child := range node.Children {
-			jsonNode.Children = append(jsonNode.Children, nodeToJSON(child))
-		}
 	}
 
 	return jsonNode
@@ -64,4 +134,3 @@ func ExportToJSONFile(root *tree.Node, filename string) error {
 
 	return ExportToJSON(root, file)
 }
-
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"dtree/internal/tree"
+)
+
+// ImportJSON reads a tree written by ExportToJSON back into nodes,
+// restoring parent links, sizes, modification times, ownership, device
+// and inode numbers, hashes, link targets, depth limits and annotations
+func ImportJSON(reader io.Reader) (*tree.Node, error) {
+	var jsonRoot JSONNode
+	if err := json.NewDecoder(reader).Decode(&jsonRoot); err != nil {
+		return nil, fmt.Errorf("invalid JSON snapshot: %w", err)
+	}
+	return jsonToNode(&jsonRoot)
+}
+
+// ImportJSONFile reads a tree from a JSON file
+func ImportJSONFile(filename string) (*tree.Node, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return ImportJSON(file)
+}
+
+func jsonToNode(jsonNode *JSONNode) (*tree.Node, error) {
+	node := &tree.Node{
+		Name:         jsonNode.Name,
+		Path:         jsonNode.Path,
+		Size:         jsonNode.Size,
+		DiskSize:     jsonNode.DiskSize,
+		Owner:        jsonNode.Owner,
+		Group:        jsonNode.Group,
+		UID:          jsonNode.UID,
+		GID:          jsonNode.GID,
+		Inode:        jsonNode.Inode,
+		Links:        jsonNode.Links,
+		Device:       jsonNode.Device,
+		Hash:         jsonNode.Hash,
+		MIME:         jsonNode.MIME,
+		Kind:         jsonNode.Kind,
+		Language:     jsonNode.Language,
+		IsLoop:       jsonNode.Recursive,
+		IsBroken:     jsonNode.Broken,
+		IsMountPoint: jsonNode.MountPoint,
+		DepthLimited: jsonNode.DepthLimit,
+		EntryCount:   jsonNode.Entries,
+		EntryBytes:   jsonNode.EntriesSize,
+		Truncated:    jsonNode.Truncated,
+		Incomplete:   jsonNode.Incomplete,
+		Children:     make([]*tree.Node, 0, len(jsonNode.Children)),
+	}
+
+	node.Lines = tree.LineCount{
+		Total:   jsonNode.Lines,
+		Blank:   jsonNode.BlankLines,
+		Comment: jsonNode.Comments,
+	}
+
+	switch jsonNode.Type {
+	case "directory":
+		node.IsDir = true
+	case "symlink":
+		node.IsSymlink = true
+		node.IsDir = jsonNode.LinkToDir
+	case "archive":
+		node.IsArchive = true
+	case "file":
+	default:
+		return nil, fmt.Errorf("invalid JSON snapshot: %q has unknown type %q", jsonNode.Name, jsonNode.Type)
+	}
+
+	if jsonNode.ModTime != "" {
+		modTime, err := time.Parse(time.RFC3339, jsonNode.ModTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON snapshot: %q has bad modTime: %w", jsonNode.Name, err)
+		}
+		node.ModTime = modTime
+	}
+	if jsonNode.Permissions != "" {
+		mode, err := tree.ParsePermissions(jsonNode.Permissions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON snapshot: %q has bad permissions: %w", jsonNode.Name, err)
+		}
+		node.Mode = mode
+	}
+	if jsonNode.Error != "" {
+		node.Err = errors.New(jsonNode.Error)
+	}
+
+	for _, jsonChild := range jsonNode.Children {
+		child, err := jsonToNode(jsonChild)
+		if err != nil {
+			return nil, err
+		}
+		node.AddChild(child)
+	}
+
+	return node, nil
+}
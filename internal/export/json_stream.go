@@ -0,0 +1,92 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"dtree/internal/tree"
+)
+
+// JSONStream writes the same document as ExportToJSON, but node by node
+// as they arrive from tree.StreamTree
+type JSONStream struct {
+	writer   io.Writer
+	children []bool // per open node: whether its children array was started
+}
+
+// NewJSONStream creates a streaming JSON exporter
+func NewJSONStream(writer io.Writer) *JSONStream {
+	return &JSONStream{writer: writer}
+}
+
+// Enter writes the opening of node's object, leaving it open for children
+func (j *JSONStream) Enter(node *tree.Node, depth int, isLast bool) error {
+	var sb strings.Builder
+
+	// Open the parent's children array on its first child
+	if depth > 0 {
+		parent := depth - 1
+		if !j.children[parent] {
+			j.children[parent] = true
+			sb.WriteString(",\n")
+			sb.WriteString(jsonIndent(parent, 1))
+			sb.WriteString("\"children\": [\n")
+		} else {
+			sb.WriteString(",\n")
+		}
+	}
+
+	// Marshal the node without children and drop the closing brace
+	data, err := json.MarshalIndent(newJSONNode(node), jsonIndent(depth, 0), "  ")
+	if err != nil {
+		return err
+	}
+	text := string(data)
+	sb.WriteString(jsonIndent(depth, 0))
+	sb.WriteString(text[:strings.LastIndexByte(text, '\n')])
+
+	j.children = append(j.children[:depth], false)
+
+	_, err = io.WriteString(j.writer, sb.String())
+	return err
+}
+
+// Leave closes node's children array, if any, and its object. The root
+// was written before the walk ended, so how it ended is added here.
+func (j *JSONStream) Leave(node *tree.Node, depth int) error {
+	var sb strings.Builder
+	if j.children[depth] {
+		sb.WriteString("\n")
+		sb.WriteString(jsonIndent(depth, 1))
+		sb.WriteString("]")
+	}
+	if depth == 0 {
+		for _, flag := range []struct {
+			key string
+			set bool
+		}{{"truncated", node.Truncated}, {"incomplete", node.Incomplete}} {
+			if flag.set {
+				sb.WriteString(",\n")
+				sb.WriteString(jsonIndent(depth, 1))
+				sb.WriteString(`"` + flag.key + `": true`)
+			}
+		}
+	}
+	sb.WriteString("\n")
+	sb.WriteString(jsonIndent(depth, 0))
+	sb.WriteString("}")
+	if depth == 0 {
+		sb.WriteString("\n")
+	}
+	j.children = j.children[:depth]
+
+	_, err := io.WriteString(j.writer, sb.String())
+	return err
+}
+
+// jsonIndent returns the indentation used by ExportToJSON for a node at
+// depth, plus extra levels
+func jsonIndent(depth int, extra int) string {
+	return strings.Repeat("  ", 2*depth+extra)
+}
@@ -1,6 +1,7 @@
 package export
 
 import (
+	"fmt"
 	"io"
 	"strings"
 
@@ -18,7 +19,7 @@ func ExportToPlain(root *tree.Node, writer io.Writer) error {
 func renderPlainNode(node *tree.Node, sb *strings.Builder, prefix string, skipRoot bool) {
 	if !skipRoot {
 		sb.WriteString(prefix)
-		sb.WriteString(node.Name)
+		sb.WriteString(tree.Annotate(node.Name, node))
 		sb.WriteString("\n")
 	}
 
@@ -28,3 +29,27 @@ func renderPlainNode(node *tree.Node, sb *strings.Builder, prefix string, skipRo
 	}
 }
 
+// PlainStream writes the plain text format as nodes arrive from
+// tree.StreamTree
+type PlainStream struct {
+	writer io.Writer
+}
+
+// NewPlainStream creates a streaming plain text exporter
+func NewPlainStream(writer io.Writer) *PlainStream {
+	return &PlainStream{writer: writer}
+}
+
+// Enter writes the line for node; the root itself is not printed
+func (p *PlainStream) Enter(node *tree.Node, depth int, isLast bool) error {
+	if depth == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(p.writer, "%s%s\n", strings.Repeat("  ", depth), tree.Annotate(node.Name, node))
+	return err
+}
+
+// Leave does nothing; lines are complete once entered
+func (p *PlainStream) Leave(node *tree.Node, depth int) error {
+	return nil
+}
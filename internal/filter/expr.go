@@ -0,0 +1,325 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"dtree/internal/glob"
+	"dtree/internal/tree"
+)
+
+// Parse compiles a filter expression such as
+//
+//	size>10M && ext==".log"
+//	!(type==d) && (age>30d || name~"*.tmp")
+//
+// Fields:
+//   - name, path, ext, type: strings; compared with ==, != or ~ (glob match)
+//   - size: bytes, with optional K/M/G/T suffix; only files match
+//   - age: time since modification, e.g. 7d or 12h
+//   - mtime: modification time, a date like 2024-01-31 or a duration ago
+//   - kind, mime: content kind and MIME type, when the walk sniffs contents
+//   - empty, file, dir, link: booleans that can be used on their own
+//
+// Conditions combine with &&, || and !, or the words and, or and not.
+func Parse(expr string) (Predicate, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, now: time.Now()}
+	pred, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q in expression", tok.text)
+	}
+	return pred, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// operators are matched longest first
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "~", "!", "(", ")"}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(expr) {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			var sb strings.Builder
+			for end < len(expr) && expr[end] != c {
+				if expr[end] == '\\' && end+1 < len(expr) {
+					end++
+				}
+				sb.WriteByte(expr[end])
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string in expression")
+			}
+			tokens = append(tokens, token{kind: tokString, text: sb.String()})
+			i = end + 1
+		case isWordChar(c):
+			end := i
+			for end < len(expr) && isWordChar(expr[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokWord, text: expr[i:end]})
+			i = end
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, token{kind: tokOp, text: op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q in expression", c)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF}), nil
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '.' || c == '-' || c == ':' || c == '+' || c == '*' || c == '/'
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	now    time.Time
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the given operators or words
+func (p *parser) accept(texts ...string) bool {
+	tok := p.peek()
+	if tok.kind != tokOp && tok.kind != tokWord {
+		return false
+	}
+	for _, text := range texts {
+		if tok.text == text {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseOr() (Predicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||", "or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or(left, right)
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Predicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&", "and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And(left, right)
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Predicate, error) {
+	if p.accept("!", "not") {
+		pred, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(pred), nil
+	}
+	if p.accept("(") {
+		pred, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing ')' in expression")
+		}
+		return pred, nil
+	}
+	return p.parseCondition()
+}
+
+func (p *parser) parseCondition() (Predicate, error) {
+	field := p.next()
+	if field.kind != tokWord {
+		if field.kind == tokEOF {
+			return nil, fmt.Errorf("unexpected end of expression")
+		}
+		return nil, fmt.Errorf("expected a field name, got %q", field.text)
+	}
+
+	op := p.peek()
+	if op.kind != tokOp || !isComparison(op.text) {
+		return booleanField(field.text)
+	}
+	p.next()
+
+	value := p.next()
+	if value.kind != tokWord && value.kind != tokString {
+		return nil, fmt.Errorf("expected a value after %s%s", field.text, op.text)
+	}
+
+	switch field.text {
+	case "name":
+		return compareString(op.text, value.text, func(n *tree.Node) string { return n.Name })
+	case "path":
+		return compareString(op.text, value.text, func(n *tree.Node) string { return n.RelPath() })
+	case "ext":
+		ext := strings.ToLower(value.text)
+		if ext != "" && op.text != "~" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		return compareString(op.text, ext, extension)
+	case "type":
+		if op.text != "~" && !validType(value.text) {
+			return nil, fmt.Errorf("unknown type %q (expected f, d, l, p or s)", value.text)
+		}
+		return compareString(op.text, value.text, TypeOf)
+	case "kind":
+		if op.text != "~" && !IsKind(value.text) {
+			return nil, fmt.Errorf("unknown kind %q (expected one of %s)", value.text, strings.Join(tree.Kinds, ", "))
+		}
+		return compareString(op.text, value.text, func(n *tree.Node) string { return n.Kind })
+	case "mime":
+		return compareString(op.text, value.text, func(n *tree.Node) string { return n.MIME })
+	case "size":
+		size, err := ParseSize(value.text)
+		if err != nil {
+			return nil, err
+		}
+		match, err := compareNumber(op.text, size, func(n *tree.Node) int64 { return n.Size })
+		if err != nil {
+			return nil, err
+		}
+		// Like --min-size and --max-size, sizes only apply to files
+		return func(n *tree.Node) bool { return !n.IsDir && match(n) }, nil
+	case "age":
+		d, err := ParseDuration(value.text)
+		if err != nil {
+			return nil, err
+		}
+		now := p.now
+		return compareNumber(op.text, int64(d), func(n *tree.Node) int64 { return int64(now.Sub(n.ModTime)) })
+	case "mtime":
+		t, err := ParseTime(value.text, p.now)
+		if err != nil {
+			return nil, err
+		}
+		return compareNumber(op.text, t.UnixNano(), func(n *tree.Node) int64 { return n.ModTime.UnixNano() })
+	default:
+		return nil, fmt.Errorf("unknown field %q", field.text)
+	}
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "~":
+		return true
+	}
+	return false
+}
+
+func booleanField(name string) (Predicate, error) {
+	switch name {
+	case "empty":
+		return Empty(), nil
+	case "file":
+		return func(n *tree.Node) bool { return TypeOf(n) == "f" }, nil
+	case "dir":
+		return func(n *tree.Node) bool { return TypeOf(n) == "d" }, nil
+	case "link":
+		return func(n *tree.Node) bool { return n.IsSymlink }, nil
+	default:
+		return nil, fmt.Errorf("%q is not a condition; expected a comparison such as %s==value", name, name)
+	}
+}
+
+func compareString(op string, value string, get func(*tree.Node) string) (Predicate, error) {
+	switch op {
+	case "==":
+		return func(n *tree.Node) bool { return get(n) == value }, nil
+	case "!=":
+		return func(n *tree.Node) bool { return get(n) != value }, nil
+	case "~":
+		pattern, err := glob.CompileWith(value, glob.Options{Braces: true})
+		if err != nil {
+			return nil, err
+		}
+		return func(n *tree.Node) bool { return pattern.Match(get(n)) }, nil
+	default:
+		return nil, fmt.Errorf("operator %s cannot be used with text; use ==, != or ~", op)
+	}
+}
+
+func compareNumber(op string, value int64, get func(*tree.Node) int64) (Predicate, error) {
+	switch op {
+	case "==":
+		return func(n *tree.Node) bool { return get(n) == value }, nil
+	case "!=":
+		return func(n *tree.Node) bool { return get(n) != value }, nil
+	case "<":
+		return func(n *tree.Node) bool { return get(n) < value }, nil
+	case "<=":
+		return func(n *tree.Node) bool { return get(n) <= value }, nil
+	case ">":
+		return func(n *tree.Node) bool { return get(n) > value }, nil
+	case ">=":
+		return func(n *tree.Node) bool { return get(n) >= value }, nil
+	default:
+		return nil, fmt.Errorf("operator %s cannot be used with numbers", op)
+	}
+}
@@ -0,0 +1,130 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"dtree/internal/tree"
+)
+
+// exprTree builds the tree the expression tests are evaluated against:
+//
+//	root/
+//	├── app.log  (20M, 40 days old)
+//	├── src/
+//	│   └── main.go  (100 bytes, 1 hour old)
+//	└── tmp/  (empty)
+func exprTree() []*tree.Node {
+	now := time.Now()
+	root := &tree.Node{Name: "root", IsDir: true, ModTime: now}
+	logFile := &tree.Node{Name: "app.log", Size: 20 << 20, ModTime: now.Add(-40 * 24 * time.Hour)}
+	src := &tree.Node{Name: "src", IsDir: true, ModTime: now.Add(-10 * time.Minute)}
+	goFile := &tree.Node{Name: "main.go", Size: 100, ModTime: now.Add(-time.Hour)}
+	tmp := &tree.Node{Name: "tmp", IsDir: true, ModTime: now.Add(-10 * time.Minute)}
+	root.AddChild(logFile)
+	root.AddChild(src)
+	src.AddChild(goFile)
+	root.AddChild(tmp)
+	return []*tree.Node{logFile, src, goFile, tmp}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // names of the matching nodes, space-separated
+	}{
+		// Fields and operators
+		{`name=="main.go"`, "main.go"},
+		{`name!="main.go"`, "app.log src tmp"},
+		{`name~"*.{go,log}"`, "app.log main.go"},
+		{`path~"src/**"`, "main.go"},
+		{`ext==go`, "main.go"},
+		{`ext==".LOG"`, "app.log"},
+		{`type==d`, "src tmp"},
+		{`type!=d`, "app.log main.go"},
+		{`empty`, "tmp"},
+		{`file`, "app.log main.go"},
+		{`name=='tmp'`, "tmp"},
+
+		// Size units; directories never match
+		{`size>10M`, "app.log"},
+		{`size>=20M`, "app.log"},
+		{`size>20MiB`, ""},
+		{`size<1.5K`, "main.go"},
+		{`size==100`, "main.go"},
+		{`size<=100b`, "main.go"},
+		{`size<1G`, "app.log main.go"},
+
+		// Duration units and dates
+		{`age>30d`, "app.log"},
+		{`age>1w && age<6w`, "app.log"},
+		{`age>90m`, "app.log"},
+		{`age<2h`, "src main.go tmp"},
+		{`mtime<7d`, "app.log"},
+		{`mtime>2000-01-01`, "app.log src main.go tmp"},
+
+		// && binds tighter than ||, and ! tighter than &&
+		{`dir || ext==go && size>1K`, "src tmp"},
+		{`ext==go || ext==log && size>1K`, "app.log main.go"},
+		{`(ext==go || ext==log) && size>1K`, "app.log"},
+		{`ext==log && size>1K || ext==go`, "app.log main.go"},
+		{`!dir && ext==go`, "main.go"},
+		{`!(dir || ext==log)`, "main.go"},
+		{`!!file`, "app.log main.go"},
+		{`((dir))`, "src tmp"},
+		{`not dir and not ext==log`, "main.go"},
+		{`dir or empty`, "src tmp"},
+	}
+
+	nodes := exprTree()
+	for _, tt := range tests {
+		pred, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		var matched []string
+		for _, node := range nodes {
+			if pred(node) {
+				matched = append(matched, node.Name)
+			}
+		}
+		if got := strings.Join(matched, " "); got != tt.want {
+			t.Errorf("Parse(%q) matches %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`size>`,
+		`size>>1`,
+		`size>abc`,
+		`size~1K`,
+		`age>soon`,
+		`mtime<yesterday`,
+		`name<a`,
+		`type==q`,
+		`kind==foo`,
+		`color==red`,
+		`bogus`,
+		`'name'=='tmp'`,
+		`empty==`,
+		`(name==a`,
+		`name==a)`,
+		`name==a &&`,
+		`&& name==a`,
+		`name==a name==b`,
+		`name=="abc`,
+		`name==a @ b`,
+		`name~"{a,b"`,
+	}
+
+	for _, expr := range tests {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package filter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"dtree/internal/tree"
+)
+
+// Predicate reports whether a node matches a condition
+type Predicate func(node *tree.Node) bool
+
+// And matches nodes that satisfy every predicate
+func And(preds ...Predicate) Predicate {
+	return func(node *tree.Node) bool {
+		for _, p := range preds {
+			if !p(node) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches nodes that satisfy at least one predicate
+func Or(preds ...Predicate) Predicate {
+	return func(node *tree.Node) bool {
+		for _, p := range preds {
+			if p(node) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not inverts a predicate
+func Not(pred Predicate) Predicate {
+	return func(node *tree.Node) bool {
+		return !pred(node)
+	}
+}
+
+// MinSize matches files of at least size bytes
+func MinSize(size int64) Predicate {
+	return func(node *tree.Node) bool {
+		return !node.IsDir && node.Size >= size
+	}
+}
+
+// MaxSize matches files of at most size bytes
+func MaxSize(size int64) Predicate {
+	return func(node *tree.Node) bool {
+		return !node.IsDir && node.Size <= size
+	}
+}
+
+// Newer matches entries modified after t
+func Newer(t time.Time) Predicate {
+	return func(node *tree.Node) bool {
+		return node.ModTime.After(t)
+	}
+}
+
+// Older matches entries modified before t
+func Older(t time.Time) Predicate {
+	return func(node *tree.Node) bool {
+		return node.ModTime.Before(t)
+	}
+}
+
+// Empty matches empty files and directories without entries.
+// Directories whose entries were not listed do not count as empty.
+func Empty() Predicate {
+	return func(node *tree.Node) bool {
+		if node.IsDir {
+			return len(node.Children) == 0 && !node.Unlisted()
+		}
+		return node.Size == 0
+	}
+}
+
+// Type matches entries of the given kinds, written as a comma-separated
+// list of f (file), d (directory), l (symlink), p (pipe) and s (socket).
+// Content kinds such as script or image (see tree.Kinds) match files
+// sniffed by the walker's Detect option.
+func Type(kinds string) (Predicate, error) {
+	var preds []Predicate
+	for _, kind := range strings.Split(kinds, ",") {
+		kind = strings.TrimSpace(kind)
+		if IsKind(kind) {
+			preds = append(preds, func(node *tree.Node) bool {
+				return node.Kind == kind
+			})
+			continue
+		}
+		if !validType(kind) {
+			return nil, fmt.Errorf("unknown type %q (expected f, d, l, p, s or one of %s)", kind, strings.Join(tree.Kinds, ", "))
+		}
+		preds = append(preds, func(node *tree.Node) bool {
+			return TypeOf(node) == kind
+		})
+	}
+	return Or(preds...), nil
+}
+
+// TypeOf returns the single-letter type of a node, as used by Type
+func TypeOf(node *tree.Node) string {
+	switch {
+	case node.IsSymlink:
+		return "l"
+	case node.IsDir:
+		return "d"
+	case node.Mode&os.ModeNamedPipe != 0:
+		return "p"
+	case node.Mode&os.ModeSocket != 0:
+		return "s"
+	default:
+		return "f"
+	}
+}
+
+// IsKind reports whether name is a content kind rather than a file type
+func IsKind(name string) bool {
+	return slices.Contains(tree.Kinds, name)
+}
+
+func validType(kind string) bool {
+	switch kind {
+	case "f", "d", "l", "p", "s":
+		return true
+	}
+	return false
+}
+
+// extension returns the lower-cased extension of a node, including the dot
+func extension(node *tree.Node) string {
+	if node.IsDir {
+		return ""
+	}
+	return strings.ToLower(filepath.Ext(node.Name))
+}
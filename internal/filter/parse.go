@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseSize parses a size such as 512, 10K, 1.5M or 2GB.
+// Units are powers of 1024, matching stats.FormatSize.
+func ParseSize(s string) (int64, error) {
+	text := strings.ToUpper(strings.TrimSpace(s))
+	text = strings.TrimSuffix(text, "IB")
+	text = strings.TrimSuffix(text, "B")
+
+	multiplier := int64(1)
+	if n := len(text); n > 0 {
+		if exp := strings.IndexByte("KMGTPE", text[n-1]); exp >= 0 {
+			multiplier = 1 << (10 * (exp + 1))
+			text = text[:n-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// ParseDuration parses a duration such as 30m, 12h, 7d or 2w, in addition
+// to everything time.ParseDuration accepts
+func ParseDuration(s string) (time.Duration, error) {
+	text := strings.TrimSpace(s)
+	if n := len(text); n > 1 {
+		unit := time.Duration(0)
+		switch text[n-1] {
+		case 'd':
+			unit = 24 * time.Hour
+		case 'w':
+			unit = 7 * 24 * time.Hour
+		case 'y':
+			unit = 365 * 24 * time.Hour
+		}
+		if unit != 0 {
+			value, err := strconv.ParseFloat(text[:n-1], 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(value * float64(unit)), nil
+		}
+	}
+
+	d, err := time.ParseDuration(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// dateLayouts are the absolute time formats accepted by ParseTime
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseTime parses either an absolute date (2024-01-31, RFC 3339, ...) or
+// a duration, which is taken as that long before now
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	text := strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	d, err := ParseDuration(text)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected a date or a duration like 7d", s)
+	}
+	return now.Add(-d), nil
+}
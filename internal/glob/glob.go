@@ -0,0 +1,192 @@
+package glob
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pattern is a compiled shell-style glob
+type Pattern struct {
+	source string
+	re     *regexp.Regexp
+}
+
+// Options controls optional glob syntax
+type Options struct {
+	IgnoreCase bool // match letters case-insensitively
+	Braces     bool // expand {a,b,c} alternatives
+}
+
+// Compile converts a glob into a Pattern using the default options.
+//
+// Supported syntax:
+//   - `*` matches any run of characters except '/'
+//   - `?` matches a single character except '/'
+//   - `[...]` matches a character class (`[!...]` negates it)
+//   - `**` as a whole path segment matches zero or more directories
+//   - `\` escapes the next character
+func Compile(pattern string) (*Pattern, error) {
+	return CompileWith(pattern, Options{})
+}
+
+// CompileWith converts a glob into a Pattern. With Options.Braces set,
+// `{a,b}` matches either alternative; alternatives may be nested.
+func CompileWith(pattern string, opts Options) (*Pattern, error) {
+	expr, err := translate(pattern, opts.Braces)
+	if err != nil {
+		return nil, err
+	}
+	flags := ""
+	if opts.IgnoreCase {
+		flags = "(?i)"
+	}
+	re, err := regexp.Compile(flags + "^" + expr + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return &Pattern{source: pattern, re: re}, nil
+}
+
+// String returns the original glob
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// Match reports whether the slash-separated name matches the pattern
+func (p *Pattern) Match(name string) bool {
+	return p.re.MatchString(name)
+}
+
+func translate(pattern string, braces bool) (string, error) {
+	var sb strings.Builder
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '{':
+			if !braces {
+				sb.WriteString(`\{`)
+				continue
+			}
+			depth++
+			sb.WriteString("(?:")
+		case '}':
+			if !braces || depth == 0 {
+				sb.WriteString(`\}`)
+				continue
+			}
+			depth--
+			sb.WriteString(")")
+		case ',':
+			if braces && depth > 0 {
+				sb.WriteString("|")
+				continue
+			}
+			sb.WriteString(",")
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				// Inside braces, an alternative is bounded by '{', ',' and '}'
+				// as well as by slashes
+				inBraces := braces && depth > 0
+				start := i == 0 || pattern[i-1] == '/' ||
+					inBraces && (pattern[i-1] == '{' || pattern[i-1] == ',')
+				j := i + 2
+				for j < len(pattern) && pattern[j] == '*' {
+					j++
+				}
+				end := j == len(pattern) || pattern[j] == '/' ||
+					inBraces && (pattern[j] == ',' || pattern[j] == '}')
+				if start && end {
+					switch {
+					case j == len(pattern) || pattern[j] != '/':
+						// Trailing "**" matches everything below
+						sb.WriteString(".*")
+					default:
+						// "**/" matches zero or more directories
+						sb.WriteString("(?:.*/)?")
+						j++
+					}
+					i = j - 1
+					continue
+				}
+				// Consecutive asterisks elsewhere behave like a single one
+				sb.WriteString("[^/]*")
+				i = j - 1
+				continue
+			}
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := classEnd(pattern, i)
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			sb.WriteString(translateClass(pattern[i+1 : end]))
+			i = end
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			} else {
+				return "", fmt.Errorf("invalid pattern %q: trailing backslash", pattern)
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if depth > 0 {
+		return "", fmt.Errorf("invalid pattern %q: unterminated '{'", pattern)
+	}
+	return sb.String(), nil
+}
+
+// classEnd returns the index of the ']' closing the class opened at start,
+// or -1 if the class is not terminated
+func classEnd(pattern string, start int) int {
+	i := start + 1
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		i++
+	}
+	// A ']' right after the opening bracket is a literal
+	if i < len(pattern) && pattern[i] == ']' {
+		i++
+	}
+	for ; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func translateClass(class string) string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	if len(class) > 0 && (class[0] == '!' || class[0] == '^') {
+		sb.WriteString("^/")
+		class = class[1:]
+	}
+	for i := 0; i < len(class); i++ {
+		c := class[i]
+		switch c {
+		case '\\':
+			if i+1 < len(class) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(class[i])))
+			}
+		case '[', ']', '^':
+			sb.WriteString(`\`)
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
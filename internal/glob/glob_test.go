@@ -0,0 +1,93 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		opts    Options
+		name    string
+		want    bool
+	}{
+		{"*.go", Options{}, "main.go", true},
+		{"*.go", Options{}, "cmd/main.go", false},
+		{"*.go", Options{}, "main.gox", false},
+		{"?.txt", Options{}, "a.txt", true},
+		{"?.txt", Options{}, "ab.txt", false},
+		{"?", Options{}, "/", false},
+		{"[abc].md", Options{}, "b.md", true},
+		{"[abc].md", Options{}, "d.md", false},
+		{"[!abc].md", Options{}, "d.md", true},
+		{"[!abc].md", Options{}, "a.md", false},
+		{"[a-c]x", Options{}, "bx", true},
+		{"[]]", Options{}, "]", true},
+		{"[", Options{}, "[", true},
+		{`\*.go`, Options{}, "*.go", true},
+		{`\*.go`, Options{}, "a.go", false},
+		{"a.b", Options{}, "axb", false},
+
+		// "**" as a whole segment
+		{"**/*.go", Options{}, "main.go", true},
+		{"**/*.go", Options{}, "a/b/main.go", true},
+		{"src/**", Options{}, "src/a/b", true},
+		{"src/**", Options{}, "src", false},
+		{"a/**/b", Options{}, "a/b", true},
+		{"a/**/b", Options{}, "a/x/y/b", true},
+		{"a/**/b", Options{}, "a/xb", false},
+		{"a**b", Options{}, "axxb", true},
+		{"a**b", Options{}, "a/b", false},
+		{"***/x", Options{}, "a/b/x", true},
+
+		// Braces
+		{"{a,b}", Options{}, "{a,b}", true},
+		{"{a,b}", Options{}, "a", false},
+		{"*.{go,md}", Options{Braces: true}, "README.md", true},
+		{"*.{go,md}", Options{Braces: true}, "main.go", true},
+		{"*.{go,md}", Options{Braces: true}, "main.c", false},
+		{"{a,b{c,d}}", Options{Braces: true}, "bd", true},
+		{"{a,b{c,d}}", Options{Braces: true}, "b", false},
+		{"{,x}y", Options{Braces: true}, "y", true},
+		{`\{a}`, Options{Braces: true}, "{a}", true},
+		{"a}", Options{Braces: true}, "a}", true},
+		{"{**/x}", Options{Braces: true}, "x", true},
+		{"{**/x}", Options{Braces: true}, "a/b/x", true},
+		{"{**/x,y}", Options{Braces: true}, "y", true},
+		{"{**/x,y}", Options{Braces: true}, "a/y", false},
+		{"src/{a,**}", Options{Braces: true}, "src/b/c", true},
+		{"{a/**,b}", Options{Braces: true}, "a/b/c", true},
+		{"{a**,b}", Options{Braces: true}, "a/b", false},
+
+		// Case
+		{"*.GO", Options{}, "main.go", false},
+		{"*.GO", Options{IgnoreCase: true}, "main.go", true},
+		{"[A-C]", Options{IgnoreCase: true}, "b", true},
+	}
+
+	for _, tt := range tests {
+		p, err := CompileWith(tt.pattern, tt.opts)
+		if err != nil {
+			t.Errorf("CompileWith(%q, %+v): %v", tt.pattern, tt.opts, err)
+			continue
+		}
+		if got := p.Match(tt.name); got != tt.want {
+			t.Errorf("CompileWith(%q, %+v).Match(%q) = %v, want %v", tt.pattern, tt.opts, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		pattern string
+		opts    Options
+	}{
+		{`abc\`, Options{}},
+		{"{a,b", Options{Braces: true}},
+		{"{a,{b}", Options{Braces: true}},
+	}
+
+	for _, tt := range tests {
+		if _, err := CompileWith(tt.pattern, tt.opts); err == nil {
+			t.Errorf("CompileWith(%q, %+v) succeeded, want an error", tt.pattern, tt.opts)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package icons
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"dtree/internal/color"
+)
+
+// Styles lists the names accepted by New
+var Styles = []string{"nerd", "emoji"}
+
+// Set chooses the icon shown before a name
+type Set struct {
+	byCategory map[color.Category]string
+	byExt      map[string]string
+	byName     map[string]string
+}
+
+// New returns the icon set of a style: "nerd" needs a Nerd Font in the
+// terminal, "emoji" works with any font that has color emoji
+func New(style string) (*Set, error) {
+	switch style {
+	case "nerd":
+		return &Set{byCategory: nerdCategories, byExt: nerdExts, byName: nerdNames}, nil
+	case "emoji":
+		return &Set{byCategory: emojiCategories, byExt: emojiExts, byName: emojiNames}, nil
+	default:
+		return nil, fmt.Errorf("unknown icon style %q (use %s)", style, strings.Join(Styles, ", "))
+	}
+}
+
+// Icon returns the icon for an entry. Files are looked up by their name,
+// then by their extension, and fall back to the icon of the category the
+// entry is colored by.
+func (s *Set) Icon(entry color.Entry) string {
+	category := color.Classify(entry)
+	if category == color.CategoryDir || category == color.CategorySymlink {
+		return s.byCategory[category]
+	}
+
+	if icon, ok := s.byName[entry.Name]; ok {
+		return icon
+	}
+	if icon, ok := s.byExt[strings.ToLower(filepath.Ext(entry.Name))]; ok {
+		return icon
+	}
+	return s.byCategory[category]
+}
+
+// IconFor returns the icon for a category
+func (s *Set) IconFor(category color.Category) string {
+	return s.byCategory[category]
+}
+
+var nerdCategories = map[color.Category]string{
+	color.CategoryFile:    "\uf15b",
+	color.CategoryDir:     "\uf07b",
+	color.CategorySymlink: "\uf481",
+	color.CategoryExec:    "\uf489",
+	color.CategoryImage:   "\uf1c5",
+	color.CategoryArchive: "\uf1c6",
+	color.CategoryCode:    "\uf1c9",
+	color.CategoryDoc:     "\uf0f6",
+}
+
+var nerdExts = map[string]string{
+	".go":    "\ue627",
+	".py":    "\ue606",
+	".js":    "\ue74e",
+	".mjs":   "\ue74e",
+	".ts":    "\ue628",
+	".rs":    "\ue7a8",
+	".c":     "\ue61e",
+	".h":     "\uf0fd",
+	".cpp":   "\ue61d",
+	".java":  "\ue738",
+	".rb":    "\ue739",
+	".php":   "\ue73d",
+	".swift": "\ue755",
+	".kt":    "\ue634",
+	".lua":   "\ue620",
+	".vim":   "\ue62b",
+	".sh":    "\ue795",
+	".bash":  "\ue795",
+	".zsh":   "\ue795",
+	".html":  "\uf13b",
+	".css":   "\ue749",
+	".md":    "\ue609",
+	".txt":   "\uf15c",
+	".pdf":   "\uf1c1",
+	".json":  "\ue60b",
+	".yaml":  "\ue615",
+	".yml":   "\ue615",
+	".toml":  "\ue615",
+	".ini":   "\ue615",
+	".sql":   "\ue706",
+	".db":    "\ue706",
+	".lock":  "\uf023",
+}
+
+var nerdNames = map[string]string{
+	"go.mod":         "\ue627",
+	"go.sum":         "\ue627",
+	"go.work":        "\ue627",
+	"Dockerfile":     "\ue7b0",
+	"Makefile":       "\ue779",
+	"GNUmakefile":    "\ue779",
+	"Cargo.toml":     "\ue7a8",
+	"package.json":   "\ue71e",
+	".gitignore":     "\ue702",
+	".gitattributes": "\ue702",
+	".gitmodules":    "\ue702",
+	"LICENSE":        "\uf02d",
+}
+
+var emojiCategories = map[color.Category]string{
+	color.CategoryFile:    "📄",
+	color.CategoryDir:     "📁",
+	color.CategorySymlink: "🔗",
+	color.CategoryExec:    "⚡",
+	color.CategoryImage:   "🎨",
+	color.CategoryArchive: "📦",
+	color.CategoryCode:    "💻",
+	color.CategoryDoc:     "📝",
+}
+
+var emojiExts = map[string]string{
+	".go":   "🐹",
+	".py":   "🐍",
+	".rs":   "🦀",
+	".java": "☕",
+	".rb":   "💎",
+	".php":  "🐘",
+	".sh":   "🐚",
+	".bash": "🐚",
+	".zsh":  "🐚",
+	".html": "🌐",
+	".css":  "🎨",
+	".pdf":  "📕",
+	".json": "🔧",
+	".yaml": "🔧",
+	".yml":  "🔧",
+	".toml": "🔧",
+	".ini":  "🔧",
+	".sql":  "💾",
+	".db":   "💾",
+	".lock": "🔒",
+}
+
+var emojiNames = map[string]string{
+	"go.mod":       "🐹",
+	"go.sum":       "🐹",
+	"go.work":      "🐹",
+	"Dockerfile":   "🐳",
+	"Makefile":     "🔨",
+	"GNUmakefile":  "🔨",
+	"Cargo.toml":   "🦀",
+	"package.json": "📦",
+	".gitignore":   "🙈",
+	"LICENSE":      "📜",
+}
@@ -2,17 +2,24 @@ package stats
 
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
-// FileStats contains statistics about files
+// FileStats contains statistics about files.
+// AddFile and AddDir are not synchronized; concurrent producers should
+// collect into their own FileStats and Merge the results.
 type FileStats struct {
-	TotalFiles      int64
-	TotalDirs       int64
-	TotalSize       int64
-	LargestFile     int64
-	OldestFile      time.Time
-	NewestFile      time.Time
+	mu sync.Mutex
+
+	TotalFiles  int64
+	TotalDirs   int64
+	TotalSize   int64
+	LargestFile int64
+	OldestFile  time.Time
+	NewestFile  time.Time
+	Errors      int64
+	DiskUsage   int64 // bytes allocated on disk, hard links counted once
 }
 
 // NewStats creates a new empty stats object
@@ -43,6 +50,39 @@ func (s *FileStats) AddDir() {
 	s.TotalDirs++
 }
 
+// AddDiskUsage adds allocated bytes; callers are responsible for counting
+// hard-linked files only once
+func (s *FileStats) AddDiskUsage(bytes int64) {
+	s.DiskUsage += bytes
+}
+
+// AddError records an entry that could not be read
+func (s *FileStats) AddError() {
+	s.Errors++
+}
+
+// Merge folds the totals of other into s. It is safe to call from
+// multiple goroutines at once.
+func (s *FileStats) Merge(other *FileStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.TotalFiles += other.TotalFiles
+	s.TotalDirs += other.TotalDirs
+	s.TotalSize += other.TotalSize
+	s.Errors += other.Errors
+	s.DiskUsage += other.DiskUsage
+	if other.LargestFile > s.LargestFile {
+		s.LargestFile = other.LargestFile
+	}
+	if other.TotalFiles > 0 && other.OldestFile.Before(s.OldestFile) {
+		s.OldestFile = other.OldestFile
+	}
+	if other.NewestFile.After(s.NewestFile) {
+		s.NewestFile = other.NewestFile
+	}
+}
+
 // FormatSize formats bytes into human-readable format
 func FormatSize(bytes int64) string {
 	const unit = 1024
@@ -57,6 +97,18 @@ func FormatSize(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
+// FormatCount formats a count in a compact way, e.g. 2.3k or 1.2M
+func FormatCount(n int64) string {
+	switch {
+	case n < 1000:
+		return fmt.Sprintf("%d", n)
+	case n < 1000000:
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	default:
+		return fmt.Sprintf("%.1fM", float64(n)/1000000)
+	}
+}
+
 // FormatDate formats a date in a compact format
 func FormatDate(t time.Time) string {
 	if t.IsZero() {
@@ -86,4 +138,3 @@ func FormatSizeCompact(bytes int64) string {
 	}
 	return fmt.Sprintf("%.1f%cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
-
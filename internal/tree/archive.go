@@ -0,0 +1,167 @@
+package tree
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// archiveMember describes one entry stored in an archive
+type archiveMember struct {
+	name    string
+	isDir   bool
+	size    int64
+	modTime time.Time
+	mode    fs.FileMode
+}
+
+// archiveFormat returns the format of an archive file from its name, or ""
+// if the name does not look like a supported archive
+func archiveFormat(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"), strings.HasSuffix(lower, ".jar"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(lower, ".tar.bz2"), strings.HasSuffix(lower, ".tbz2"):
+		return "tar.bz2"
+	default:
+		return ""
+	}
+}
+
+// expandArchive reads the archive at fsPath and adds its members below
+// node. Members deeper than maxLevel below the archive are left out
+// (0 = unlimited).
+func (w *walker) expandArchive(node *Node, fsPath string, format string, maxLevel int) {
+	// Keep whatever was read before a failure
+	members, err := readArchive(w.fsys, fsPath, format)
+	if err != nil {
+		node.Err = newWalkError("reading archive", err)
+	}
+
+	builder := newPathBuilder(node, node.GetFullPath())
+	for _, member := range members {
+		name := strings.Trim(path.Clean("/"+member.name), "/")
+		if name == "" {
+			continue
+		}
+		if maxLevel > 0 && strings.Count(name, "/")+1 > maxLevel {
+			continue
+		}
+		if !w.options.ShowHidden && hasHiddenPart(name) {
+			continue
+		}
+		if excluded(w.options, path.Join(fsPath, name), member.isDir) {
+			continue
+		}
+
+		child := builder.add(name, member.isDir)
+		child.Size = member.size
+		child.ModTime = member.modTime
+		child.Mode = member.mode
+	}
+	sortChildren(node)
+}
+
+// hasHiddenPart reports whether any element of a slash-separated path is hidden
+func hasHiddenPart(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+func readArchive(fsys fs.FS, fsPath string, format string) ([]archiveMember, error) {
+	file, err := fsys.Open(fsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch format {
+	case "zip":
+		return readZip(file)
+	case "tar.gz":
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return readTar(gz)
+	case "tar.bz2":
+		return readTar(bzip2.NewReader(file))
+	default:
+		return readTar(file)
+	}
+}
+
+func readZip(file fs.File) ([]archiveMember, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// zip needs random access; read the whole file if it isn't seekable
+	reader, ok := file.(io.ReaderAt)
+	size := info.Size()
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+		size = int64(len(data))
+	}
+
+	archive, err := zip.NewReader(reader, size)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]archiveMember, 0, len(archive.File))
+	for _, f := range archive.File {
+		members = append(members, archiveMember{
+			name:    f.Name,
+			isDir:   f.FileInfo().IsDir(),
+			size:    int64(f.UncompressedSize64),
+			modTime: f.Modified,
+			mode:    f.Mode(),
+		})
+	}
+	return members, nil
+}
+
+func readTar(reader io.Reader) ([]archiveMember, error) {
+	var members []archiveMember
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return members, nil
+		}
+		if err != nil {
+			return members, err
+		}
+		info := header.FileInfo()
+		members = append(members, archiveMember{
+			name:    header.Name,
+			isDir:   info.IsDir(),
+			size:    header.Size,
+			modTime: header.ModTime,
+			mode:    info.Mode(),
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package tree
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// pathBuilder assembles a subtree from slash-separated paths given in any
+// order, creating the intermediate directories on the way
+type pathBuilder struct {
+	root     *Node
+	basePath string           // Node.Path of the root's children
+	dirs     map[string]*Node // directories created so far, by relative path
+}
+
+func newPathBuilder(root *Node, basePath string) *pathBuilder {
+	return &pathBuilder{
+		root:     root,
+		basePath: basePath,
+		dirs:     map[string]*Node{"": root},
+	}
+}
+
+// add inserts the entry at relPath and returns its node. If the entry
+// already exists (for instance as an implicit parent directory), the
+// existing node is returned.
+func (b *pathBuilder) add(relPath string, isDir bool) *Node {
+	relPath = strings.Trim(path.Clean("/"+relPath), "/")
+	if relPath == "" {
+		return b.root
+	}
+	if node, ok := b.dirs[relPath]; ok {
+		return node
+	}
+
+	dir, name := path.Split(relPath)
+	dir = strings.TrimSuffix(dir, "/")
+	parent := b.add(dir, true)
+
+	// The entry may have been seen before as a file; reuse it, turning it
+	// into a directory if something is being placed below it
+	for _, child := range parent.Children {
+		if child.Name == name {
+			if isDir {
+				child.IsDir = true
+				b.dirs[relPath] = child
+			}
+			return child
+		}
+	}
+
+	node := &Node{
+		Name:     name,
+		Path:     b.parentPath(dir),
+		IsDir:    isDir,
+		Children: make([]*Node, 0),
+	}
+	parent.AddChild(node)
+	if isDir {
+		b.dirs[relPath] = node
+	}
+	return node
+}
+
+func (b *pathBuilder) parentPath(dir string) string {
+	return path.Join(b.basePath, dir)
+}
+
+// sortChildren orders every directory by name, as os.ReadDir would
+func sortChildren(node *Node) {
+	sort.Slice(node.Children, func(i, j int) bool {
+		return node.Children[i].Name < node.Children[j].Name
+	})
+	for _, child := range node.Children {
+		sortChildren(child)
+	}
+}
@@ -0,0 +1,65 @@
+package tree
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Charset holds the prefixes used to draw the branches of a tree
+type Charset struct {
+	Branch string // before an entry that has siblings after it
+	Last   string // before the last entry of a directory
+	Pipe   string // below an entry that has siblings after it
+	Space  string // below the last entry of a directory
+}
+
+// DefaultCharset draws the tree with Unicode box-drawing characters
+var DefaultCharset = Charset{
+	Branch: "├── ",
+	Last:   "└── ",
+	Pipe:   "│   ",
+	Space:  "    ",
+}
+
+// lineStyle lists the characters of a charset: the tee, the corner of the
+// last entry, the vertical line and the horizontal line
+type lineStyle struct {
+	tee, corner, vertical, horizontal string
+}
+
+// Charsets lists the names accepted by NewCharset
+var Charsets = []string{"ascii", "unicode", "rounded", "heavy", "double"}
+
+var lineStyles = map[string]lineStyle{
+	"ascii":   {"|", "`", "|", "-"},
+	"unicode": {"├", "└", "│", "─"},
+	"rounded": {"├", "╰", "│", "─"},
+	"heavy":   {"┣", "┗", "┃", "━"},
+	"double":  {"╠", "╚", "║", "═"},
+}
+
+// NewCharset builds a charset from a style name and the number of columns
+// each level of the tree is indented by. The default tree uses "unicode"
+// and 4.
+func NewCharset(name string, indent int) (Charset, error) {
+	style, ok := lineStyles[name]
+	if !ok {
+		return Charset{}, fmt.Errorf("unknown charset %q (use %s)", name, strings.Join(Charsets, ", "))
+	}
+	if indent < 1 {
+		return Charset{}, fmt.Errorf("indent must be at least 1, got %d", indent)
+	}
+
+	// The connector is followed by a space once there is room for one
+	line, gap := "", ""
+	if indent >= 2 {
+		line = strings.Repeat(style.horizontal, indent-2)
+		gap = " "
+	}
+	return Charset{
+		Branch: style.tee + line + gap,
+		Last:   style.corner + line + gap,
+		Pipe:   style.vertical + strings.Repeat(" ", indent-1),
+		Space:  strings.Repeat(" ", indent),
+	}, nil
+}
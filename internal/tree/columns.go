@@ -0,0 +1,179 @@
+package tree
+
+import (
+	"fmt"
+
+	"dtree/internal/stats"
+)
+
+// sizeColumn shows file sizes, and the total size of the files below each
+// directory in brackets
+type sizeColumn struct {
+	diskUsage bool
+	long      bool
+
+	dirSize  map[*Node]int64 // apparent bytes below each directory
+	dirUsage map[*Node]int64 // allocated bytes below each directory
+}
+
+// NewSizeColumn creates a size column. With diskUsage it shows the space
+// allocated on disk, like du, counting hard-linked files once in directory
+// totals; with long as well, apparent and allocated sizes are shown side
+// by side.
+func NewSizeColumn(diskUsage, long bool) Column {
+	return &sizeColumn{diskUsage: diskUsage, long: long}
+}
+
+// Prepare computes the directory totals
+func (c *sizeColumn) Prepare(root *Node) {
+	c.dirSize = make(map[*Node]int64)
+	calculateDirSize(root, c.dirSize)
+	if c.diskUsage {
+		c.dirUsage = calculateDiskUsage(root)
+	}
+}
+
+func (c *sizeColumn) Value(node *Node) string {
+	// The total of a directory whose entries were left out is not known
+	if node.IsDir && node.Unlisted() {
+		return ""
+	}
+	if node.IsDir {
+		return "[" + c.format(c.dirSize[node], c.dirUsage[node]) + "]"
+	}
+	return c.format(node.Size, node.DiskSize)
+}
+
+// format formats an entry size according to the size mode
+func (c *sizeColumn) format(apparent, allocated int64) string {
+	switch {
+	case c.diskUsage && c.long:
+		return stats.FormatSizeCompact(apparent) + "/" + stats.FormatSizeCompact(allocated)
+	case c.diskUsage:
+		return stats.FormatSizeCompact(allocated)
+	default:
+		return stats.FormatSizeCompact(apparent)
+	}
+}
+
+// calculateDirSize fills sizes with the apparent size of every directory
+// below node, including those inside archives, and returns the size of node
+func calculateDirSize(node *Node, sizes map[*Node]int64) int64 {
+	var size int64
+	for _, child := range node.Children {
+		size += calculateDirSize(child, sizes)
+	}
+	if !node.IsDir {
+		// An archive takes its own size, not that of its members
+		return node.Size
+	}
+	sizes[node] = size
+	return size
+}
+
+// calculateDiskUsage returns the allocated size of every directory below
+// root, counting each file identity once across the tree
+func calculateDiskUsage(root *Node) map[*Node]int64 {
+	usage := make(map[*Node]int64)
+	seen := make(map[fileID]bool)
+
+	var walk func(node *Node) int64
+	walk = func(node *Node) int64 {
+		var size int64
+		id := fileID{dev: node.Device, ino: node.Inode}
+		if node.Inode == 0 || !seen[id] {
+			seen[id] = true
+			size = node.DiskSize
+		}
+
+		// Archive members take no space of their own
+		if !node.IsArchive {
+			for _, child := range node.Children {
+				size += walk(child)
+			}
+		}
+
+		if node.IsDir {
+			usage[node] = size
+		}
+		return size
+	}
+	walk(root)
+	return usage
+}
+
+// NewDateColumn creates a column of modification dates, with the time of
+// day when long is set
+func NewDateColumn(long bool) Column {
+	return ColumnFunc(func(node *Node) string {
+		if long {
+			return stats.FormatDateLong(node.ModTime)
+		}
+		return stats.FormatDate(node.ModTime)
+	})
+}
+
+// NewInodeColumn creates a column of inode numbers. Values that are not
+// known, as for archive members, are shown as "-".
+func NewInodeColumn() Column {
+	return ColumnFunc(func(node *Node) string {
+		if node.Inode == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%d", node.Inode)
+	})
+}
+
+// NewPermissionsColumn creates a column of ls-style permission strings
+func NewPermissionsColumn() Column {
+	return ColumnFunc(func(node *Node) string {
+		return node.Permissions()
+	})
+}
+
+// NewOwnerColumn creates a column of owning users
+func NewOwnerColumn() Column {
+	return ColumnFunc(func(node *Node) string {
+		return orDash(node.OwnerName())
+	})
+}
+
+// NewGroupColumn creates a column of owning groups
+func NewGroupColumn() Column {
+	return ColumnFunc(func(node *Node) string {
+		return orDash(node.GroupName())
+	})
+}
+
+// NewHashColumn creates a column of the hashes set by HashTree
+func NewHashColumn() Column {
+	return ColumnFunc(func(node *Node) string {
+		return orDash(node.Hash)
+	})
+}
+
+// NewLinesColumn creates a column of the line counts set by CountLines,
+// naming the main language of directories, e.g. "[2.3k lines Go]"
+func NewLinesColumn() Column {
+	return ColumnFunc(func(node *Node) string {
+		if node.Language == "" {
+			return ""
+		}
+		lines := stats.FormatCount(node.Lines.Total) + " lines"
+		if node.IsDir {
+			if node.Language != OtherLanguage {
+				lines += " " + node.Language
+			}
+			lines = "[" + lines + "]"
+		}
+		return lines
+	})
+}
+
+// orDash returns s, or "-" if s is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
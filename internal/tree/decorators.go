@@ -0,0 +1,59 @@
+package tree
+
+import (
+	"net/url"
+	"path/filepath"
+
+	"dtree/internal/color"
+	"dtree/internal/icons"
+)
+
+// NewColorDecorator colors names according to their type using theme
+func NewColorDecorator(theme *color.Theme) Decorator {
+	return DecoratorFunc(func(node *Node, name string) string {
+		if node.IsArchive {
+			return theme.ColorizeArchive(name)
+		}
+		return theme.ColorizeEntry(color.Entry{
+			Name:      name,
+			IsDir:     node.IsDir,
+			IsSymlink: node.IsSymlink,
+			Mode:      node.Mode,
+			Kind:      node.Kind,
+			IsOrphan:  node.IsBroken,
+		})
+	})
+}
+
+// NewIconDecorator puts an icon from set before names, chosen by the same
+// classification as their color
+func NewIconDecorator(set *icons.Set) Decorator {
+	return DecoratorFunc(func(node *Node, name string) string {
+		icon := set.IconFor(color.CategoryArchive)
+		if !node.IsArchive {
+			icon = set.Icon(color.Entry{
+				Name:      node.Name,
+				IsDir:     node.IsDir,
+				IsSymlink: node.IsSymlink,
+				Mode:      node.Mode,
+				Kind:      node.Kind,
+			})
+		}
+		return icon + " " + name
+	})
+}
+
+// NewLinkDecorator turns names into terminal hyperlinks (OSC 8) to the
+// files below rootPath, so they can be opened by clicking them
+func NewLinkDecorator(rootPath string) Decorator {
+	return DecoratorFunc(func(node *Node, name string) string {
+		// Archive members have no file of their own
+		for parent := node.Parent; parent != nil; parent = parent.Parent {
+			if parent.IsArchive {
+				return name
+			}
+		}
+		target := url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(rootPath, filepath.FromSlash(node.RelPath())))}
+		return "\033]8;;" + target.String() + "\033\\" + name + "\033]8;;\033\\"
+	})
+}
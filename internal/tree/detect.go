@@ -0,0 +1,141 @@
+package tree
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Content kinds stored in Node.Kind by --detect
+const (
+	KindImage      = "image"
+	KindArchive    = "archive"
+	KindDocument   = "document"
+	KindExecutable = "executable"
+	KindScript     = "script"
+	KindCode       = "code"
+	KindText       = "text"
+	KindBinary     = "binary"
+)
+
+// Kinds lists every content kind, for validating user input
+var Kinds = []string{KindImage, KindArchive, KindDocument, KindExecutable, KindScript, KindCode, KindText, KindBinary}
+
+// sniffLen is how much of a file is read to detect its type. It covers
+// the tar header magic at offset 257.
+const sniffLen = 512
+
+// magic describes a file signature that http.DetectContentType does not know
+type magic struct {
+	offset int
+	prefix string
+	mime   string
+	kind   string
+}
+
+var magics = []magic{
+	{0, "\x7fELF", "application/x-executable", KindExecutable},
+	{0, "\xfe\xed\xfa\xce", "application/x-mach-binary", KindExecutable},
+	{0, "\xfe\xed\xfa\xcf", "application/x-mach-binary", KindExecutable},
+	{0, "\xce\xfa\xed\xfe", "application/x-mach-binary", KindExecutable},
+	{0, "\xcf\xfa\xed\xfe", "application/x-mach-binary", KindExecutable},
+	{0, "MZ", "application/vnd.microsoft.portable-executable", KindExecutable},
+	{0, "\x00asm", "application/wasm", KindExecutable},
+	{0, "BZh", "application/x-bzip2", KindArchive},
+	{0, "\xfd7zXZ\x00", "application/x-xz", KindArchive},
+	{0, "7z\xbc\xaf\x27\x1c", "application/x-7z-compressed", KindArchive},
+	{0, "\x28\xb5\x2f\xfd", "application/zstd", KindArchive},
+	{257, "ustar", "application/x-tar", KindArchive},
+}
+
+// wellKnownNames maps build files without a telling extension to their
+// MIME type; they are all of KindCode
+var wellKnownNames = map[string]string{
+	"Dockerfile":    "text/x-dockerfile",
+	"Containerfile": "text/x-dockerfile",
+	"Makefile":      "text/x-makefile",
+	"GNUmakefile":   "text/x-makefile",
+	"Jenkinsfile":   "text/x-groovy",
+	"Vagrantfile":   "text/x-ruby",
+	"go.mod":        "text/x-go-mod",
+}
+
+// detectType sniffs the start of a file and sets MIME and Kind on node.
+// Files that cannot be read are left undetected.
+func (w *walker) detectType(node *Node, relPath string) {
+	file, err := w.fsys.Open(relPath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return
+	}
+	node.MIME, node.Kind = DetectType(node.Name, header[:n])
+}
+
+// DetectType returns the MIME type and kind of a file from its name and
+// the first bytes of its contents: magic numbers first, then shebang
+// lines, then the text vs binary heuristic of http.DetectContentType.
+func DetectType(name string, header []byte) (mime, kind string) {
+	for _, m := range magics {
+		if len(header) >= m.offset+len(m.prefix) && string(header[m.offset:m.offset+len(m.prefix)]) == m.prefix {
+			return m.mime, m.kind
+		}
+	}
+
+	if bytes.HasPrefix(header, []byte("#!")) {
+		return "text/x-script." + interpreter(header), KindScript
+	}
+
+	mime = http.DetectContentType(header)
+	// Drop parameters such as "; charset=utf-8"
+	if i := strings.IndexByte(mime, ';'); i >= 0 {
+		mime = mime[:i]
+	}
+
+	switch {
+	case strings.HasPrefix(mime, "image/"):
+		return mime, KindImage
+	case mime == "application/zip" || mime == "application/x-gzip" ||
+		mime == "application/x-rar-compressed" || mime == "application/vnd.rar":
+		return mime, KindArchive
+	case mime == "application/pdf" || mime == "application/postscript":
+		return mime, KindDocument
+	case strings.HasPrefix(mime, "text/"):
+		if known, ok := wellKnownNames[path.Base(name)]; ok {
+			return known, KindCode
+		}
+		return mime, KindText
+	default:
+		return mime, KindBinary
+	}
+}
+
+// interpreter returns the program named by a shebang line, skipping env,
+// e.g. "python3" for "#!/usr/bin/env python3"
+func interpreter(header []byte) string {
+	line := header[2:]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	program := path.Base(fields[0])
+	if program == "env" {
+		for _, field := range fields[1:] {
+			// Skip options such as env -S
+			if !strings.HasPrefix(field, "-") {
+				return path.Base(field)
+			}
+		}
+	}
+	return program
+}
@@ -0,0 +1,280 @@
+package tree
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DupeGroup is a set of files, or of directories, with identical contents
+type DupeGroup struct {
+	ID    int     // position of the group in tree order, starting at 1
+	Nodes []*Node // the copies, in tree order
+	Size  int64   // size of one copy; the total file size for directories
+	IsDir bool
+}
+
+// Reclaimable returns the bytes freed by keeping a single copy. Copies
+// inside a duplicated directory are already covered by that directory
+// and count as one.
+func (g *DupeGroup) Reclaimable() int64 {
+	copies := 0
+	nested := false
+	for _, node := range g.Nodes {
+		if node.Parent != nil && insideDuplicateDir(node.Parent) {
+			nested = true
+		} else {
+			copies++
+		}
+	}
+	if nested {
+		copies++
+	}
+	return int64(copies-1) * g.Size
+}
+
+// DupeOptions controls FindDuplicates
+type DupeOptions struct {
+	Algorithm string // hash algorithm used to confirm duplicates, see HashTree
+	MinSize   int64  // files smaller than this are never reported
+	Jobs      int    // files hashed in parallel (0 = number of CPUs)
+}
+
+// dupeFinder holds the state of a FindDuplicates run
+type dupeFinder struct {
+	options DupeOptions
+	newHash func() hash.Hash
+	order   map[*Node]int    // preorder position of every node
+	dirHash map[*Node]string // Merkle hash of directories whose files were all hashed
+	aliases map[*Node]bool   // further hard links to a file that is already listed
+}
+
+// FindDuplicates finds files and directories of a tree walked from fsys
+// that have identical contents, and labels every copy "[dup #N, M copies]".
+// Files are grouped by size first, so only files that share their size
+// with another file are read. Hard links to the same file are not
+// duplicates of each other. A directory is reported instead of its
+// contents when it is a copy of another directory.
+//
+// If ctx ends early, hashing stops, the root is marked Incomplete, and the
+// groups found so far are returned.
+func FindDuplicates(ctx context.Context, fsys fs.FS, root *Node, options DupeOptions) ([]*DupeGroup, error) {
+	newHash, err := newHasher(options.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	f := &dupeFinder{
+		options: options,
+		newHash: newHash,
+		order:   make(map[*Node]int),
+		dirHash: make(map[*Node]string),
+		aliases: make(map[*Node]bool),
+	}
+
+	// Group by size, keeping one node per hard-linked file
+	bySize := make(map[int64][]*Node)
+	links := make(map[fileID][]*Node)
+	f.collect(root, bySize, links)
+
+	var candidates []*Node
+	for _, nodes := range bySize {
+		if len(nodes) > 1 {
+			candidates = append(candidates, nodes...)
+		}
+	}
+	hashFiles(ctx, fsys, newHash, options.Jobs, nil, func(files chan<- *Node) {
+		for _, node := range candidates {
+			select {
+			case files <- node:
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+	if ctx.Err() != nil {
+		root.Incomplete = true
+	}
+
+	// Hard links share the hash of the node that was read
+	for _, nodes := range links {
+		for _, alias := range nodes[1:] {
+			alias.Hash = nodes[0].Hash
+		}
+	}
+
+	// Directories are only compared when every file below them was hashed
+	merkleHash(root, f.newHash(), func(dir *Node, digest string, complete bool) {
+		if complete {
+			f.dirHash[dir] = digest
+		}
+	})
+	groups := f.group(root)
+	return groups, nil
+}
+
+// FindDuplicatesPath finds duplicates in a tree walked from rootPath on the
+// OS filesystem
+func FindDuplicatesPath(ctx context.Context, rootPath string, root *Node, options DupeOptions) ([]*DupeGroup, error) {
+	absPath, err := filepath.Abs(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	return FindDuplicates(ctx, os.DirFS(absPath), root, options)
+}
+
+// collect numbers the nodes in preorder and groups the readable regular
+// files by size
+func (f *dupeFinder) collect(node *Node, bySize map[int64][]*Node, links map[fileID][]*Node) {
+	f.order[node] = len(f.order)
+
+	if !node.IsDir {
+		if node.Err != nil || !node.Mode.IsRegular() {
+			return
+		}
+		if node.Inode != 0 && node.Links > 1 {
+			id := fileID{dev: node.Device, ino: node.Inode}
+			links[id] = append(links[id], node)
+			if len(links[id]) > 1 {
+				f.aliases[node] = true
+				return
+			}
+		}
+		bySize[node.Size] = append(bySize[node.Size], node)
+		return
+	}
+
+	// Archive members cannot be opened through fsys
+	if node.IsArchive {
+		return
+	}
+	for _, child := range node.Children {
+		f.collect(child, bySize, links)
+	}
+}
+
+// group builds the duplicate groups, outermost directories first, and
+// labels their members
+func (f *dupeFinder) group(root *Node) []*DupeGroup {
+	dirs := make(map[string]*DupeGroup)
+	files := make(map[string]*DupeGroup)
+	for node := range f.order {
+		switch {
+		case node == root:
+		case node.IsDir && !node.IsArchive:
+			digest, ok := f.dirHash[node]
+			size := directorySize(node)
+			if !ok || size == 0 || size < f.options.MinSize {
+				continue
+			}
+			addToGroup(dirs, digest, node, size, true)
+		case f.aliases[node]:
+		case node.Hash != "" && node.Size > 0 && node.Size >= f.options.MinSize:
+			addToGroup(files, node.Hash, node, node.Size, false)
+		}
+	}
+
+	var groups []*DupeGroup
+	// Directories go first so that copies inside them can be recognised
+	for _, candidates := range []map[string]*DupeGroup{dirs, files} {
+		for _, group := range f.sorted(candidates) {
+			if len(group.Nodes) < 2 || f.allNested(group) {
+				continue
+			}
+			for _, node := range group.Nodes {
+				node.dupe = group
+			}
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return f.order[groups[i].Nodes[0]] < f.order[groups[j].Nodes[0]]
+	})
+	for i, group := range groups {
+		group.ID = i + 1
+		for _, node := range group.Nodes {
+			node.Label = fmt.Sprintf("[dup #%d, %d copies]", group.ID, len(group.Nodes))
+		}
+	}
+	return groups
+}
+
+// sorted returns the groups ordered by the position of their first member,
+// with the members themselves in tree order
+func (f *dupeFinder) sorted(groups map[string]*DupeGroup) []*DupeGroup {
+	list := make([]*DupeGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.Slice(group.Nodes, func(i, j int) bool {
+			return f.order[group.Nodes[i]] < f.order[group.Nodes[j]]
+		})
+		list = append(list, group)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return f.order[list[i].Nodes[0]] < f.order[list[j].Nodes[0]]
+	})
+	return list
+}
+
+// allNested reports whether every member of group lies inside a directory
+// that is already reported as a duplicate
+func (f *dupeFinder) allNested(group *DupeGroup) bool {
+	for _, node := range group.Nodes {
+		if node.Parent == nil || !insideDuplicateDir(node.Parent) {
+			return false
+		}
+	}
+	return true
+}
+
+// insideDuplicateDir reports whether node or one of its ancestors is a
+// reported duplicate directory
+func insideDuplicateDir(node *Node) bool {
+	for ; node != nil; node = node.Parent {
+		if node.dupe != nil && node.dupe.IsDir {
+			return true
+		}
+	}
+	return false
+}
+
+// addToGroup adds node to the group of nodes with the same digest
+func addToGroup(groups map[string]*DupeGroup, digest string, node *Node, size int64, isDir bool) {
+	group, ok := groups[digest]
+	if !ok {
+		group = &DupeGroup{Size: size, IsDir: isDir}
+		groups[digest] = group
+	}
+	group.Nodes = append(group.Nodes, node)
+}
+
+// directorySize returns the total size of the files below node
+func directorySize(node *Node) int64 {
+	var size int64
+	for _, child := range node.Children {
+		if child.IsDir && !child.IsArchive {
+			size += directorySize(child)
+		} else {
+			size += child.Size
+		}
+	}
+	return size
+}
+
+// KeepDuplicates removes everything from the tree except the duplicates
+// labelled by FindDuplicates and the directories leading to them. The
+// contents of duplicate directories are hidden, as they are identical.
+func KeepDuplicates(root *Node) {
+	filterTree(root, func(node *Node) bool {
+		if node.dupe == nil {
+			return false
+		}
+		if node.dupe.IsDir {
+			node.Children = node.Children[:0]
+		}
+		return true
+	})
+}
@@ -0,0 +1,89 @@
+package tree
+
+import (
+	"path"
+
+	"dtree/internal/glob"
+)
+
+// ApplyOptions applies the filtering options of a walk to a tree that was
+// built some other way, such as one loaded from a snapshot: ShowHidden,
+// MaxDepth, the include/ignore patterns, Filter and Prune
+func ApplyOptions(root *Node, options WalkerOptions) {
+	trimTree(root, options, 0)
+	finishTree(root, options)
+}
+
+func trimTree(node *Node, options WalkerOptions, depth int) {
+	if options.MaxDepth > 0 && depth >= options.MaxDepth {
+		node.DepthLimited = node.DepthLimited || len(node.Children) > 0
+		node.Children = node.Children[:0]
+		return
+	}
+
+	kept := node.Children[:0]
+	for _, child := range node.Children {
+		if !options.ShowHidden && child.IsHidden() {
+			continue
+		}
+		if excluded(options, child.RelPath(), child.IsDir || child.IsArchive) {
+			continue
+		}
+		trimTree(child, options, depth+1)
+		kept = append(kept, child)
+	}
+	node.Children = kept
+}
+
+// matchAny reports whether name or relPath matches one of the patterns
+func matchAny(patterns []*glob.Pattern, name string, relPath string) bool {
+	for _, p := range patterns {
+		if p.Match(name) || p.Match(relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded reports whether an entry is filtered out by the pattern options.
+// Include patterns only apply to files so that directories (and listed
+// archives) leading to matches stay reachable.
+func excluded(options WalkerOptions, relPath string, isDir bool) bool {
+	name := path.Base(relPath)
+	if matchAny(options.IgnorePatterns, name, relPath) {
+		return true
+	}
+	if !isDir && len(options.Patterns) > 0 && !matchAny(options.Patterns, name, relPath) {
+		return true
+	}
+	return false
+}
+
+// filterTree keeps the nodes accepted by keep along with their ancestors.
+// The predicate sees each node before its children are filtered. It
+// returns true if node itself should be kept.
+func filterTree(node *Node, keep func(*Node) bool) bool {
+	matched := keep(node)
+	kept := node.Children[:0]
+	for _, child := range node.Children {
+		if filterTree(child, keep) {
+			kept = append(kept, child)
+		}
+	}
+	node.Children = kept
+	return matched || len(node.Children) > 0
+}
+
+// pruneEmptyDirs removes directories, and listed archives, that have no
+// descendants left. Directories whose entries were never listed are kept.
+// It returns true if node itself should be removed.
+func pruneEmptyDirs(node *Node) bool {
+	kept := node.Children[:0]
+	for _, child := range node.Children {
+		if !pruneEmptyDirs(child) {
+			kept = append(kept, child)
+		}
+	}
+	node.Children = kept
+	return (node.IsDir || node.IsArchive) && len(node.Children) == 0 && !node.Unlisted()
+}
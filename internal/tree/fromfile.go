@@ -0,0 +1,97 @@
+package tree
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// BuildFromPaths builds a tree from a list of paths instead of walking the
+// disk, such as the output of `git ls-files`, `find -print0` or `tar -t`.
+// Paths are separated by newlines, or by NUL bytes if the input contains
+// any. A trailing slash marks a directory, and parent directories are
+// created as needed. Paths that exist on disk (relative to the working
+// directory) are stat'ed for their size, date and type; the others are
+// still placed in the tree.
+//
+// ShowHidden, MaxDepth, the include/ignore patterns, Filter and Prune are
+// honoured. Absolute paths are listed relative to "/".
+func BuildFromPaths(reader io.Reader, options WalkerOptions) (*Node, error) {
+	paths, err := readPathList(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &Node{
+		Name:     ".",
+		IsDir:    true,
+		Children: make([]*Node, 0),
+	}
+	builder := newPathBuilder(root, "")
+
+	for _, p := range paths {
+		isDir := strings.HasSuffix(p, "/")
+		rel := strings.Trim(path.Clean("/"+filepath.ToSlash(p)), "/")
+		if rel == "" {
+			continue
+		}
+		if !options.ShowHidden && hasHiddenPart(rel) {
+			continue
+		}
+		if options.MaxDepth > 0 && strings.Count(rel, "/")+1 > options.MaxDepth {
+			// Keep the directory at the depth limit, as a walk would
+			parts := strings.SplitN(rel, "/", options.MaxDepth+1)
+			limit := strings.Join(parts[:options.MaxDepth], "/")
+			if !excluded(options, limit, true) {
+				builder.add(limit, true).DepthLimited = true
+			}
+			continue
+		}
+
+		info, statErr := os.Lstat(p)
+		if statErr == nil && info.IsDir() {
+			isDir = true
+		}
+		if excluded(options, rel, isDir) {
+			continue
+		}
+
+		node := builder.add(rel, isDir)
+		if statErr == nil {
+			node.IsSymlink = info.Mode()&os.ModeSymlink != 0
+			node.Size = info.Size()
+			node.ModTime = info.ModTime()
+			node.Mode = info.Mode()
+			fillSysInfo(node, info)
+		}
+	}
+
+	sortChildren(root)
+	finishTree(root, options)
+	return root, nil
+}
+
+// readPathList splits the input into paths, dropping empty entries
+func readPathList(reader io.Reader) ([]string, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	separator := byte('\n')
+	if bytes.IndexByte(data, 0) >= 0 {
+		separator = 0
+	}
+
+	var paths []string
+	for _, line := range bytes.Split(data, []byte{separator}) {
+		p := strings.TrimSuffix(string(line), "\r")
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,255 @@
+package tree
+
+import (
+	"bufio"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"dtree/internal/glob"
+)
+
+// gitignoreRule is a single pattern line from an ignore file
+type gitignoreRule struct {
+	pattern  *glob.Pattern
+	negate   bool
+	dirOnly  bool
+	anchored bool
+	base     string // directory the rule is relative to, slash-separated, relative to the repository root
+}
+
+// GitIgnore holds the ignore rules that apply to one directory.
+// Rules are ordered from lowest to highest precedence; the last match wins.
+type GitIgnore struct {
+	rules  []gitignoreRule
+	prefix string // path of the walk root relative to the repository root
+}
+
+// LoadGitIgnore collects the ignore rules that apply to root: the user's
+// global excludes file, .git/info/exclude and every .gitignore from the
+// repository top level down to root itself. Nested .gitignore files below
+// root are read from the walked filesystem as the walker descends.
+func LoadGitIgnore(root string) (*GitIgnore, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	top := findRepoRoot(absRoot)
+	if top == "" {
+		// Not inside a repository: only root-level files apply
+		top = absRoot
+	}
+
+	prefix, err := filepath.Rel(top, absRoot)
+	if err != nil {
+		return nil, err
+	}
+	prefix = filepath.ToSlash(prefix)
+	if prefix == "." {
+		prefix = ""
+	}
+
+	gi := &GitIgnore{prefix: prefix}
+
+	if file := globalExcludesFile(); file != "" {
+		gi.rules = append(gi.rules, readIgnoreFile(file, "")...)
+	}
+	gi.rules = append(gi.rules, readIgnoreFile(filepath.Join(top, ".git", "info", "exclude"), "")...)
+
+	// .gitignore files from the repository top down to the walk root
+	dir := ""
+	gi.rules = append(gi.rules, readIgnoreFile(filepath.Join(top, ".gitignore"), dir)...)
+	if prefix != "" {
+		for _, part := range strings.Split(prefix, "/") {
+			dir = path.Join(dir, part)
+			gi.rules = append(gi.rules, readIgnoreFile(filepath.Join(top, filepath.FromSlash(dir), ".gitignore"), dir)...)
+		}
+	}
+
+	return gi, nil
+}
+
+// Enter returns the rules for the subdirectory relDir of the walked
+// filesystem, adding the rules from its own .gitignore file if it has one
+func (gi *GitIgnore) Enter(fsys fs.FS, relDir string) *GitIgnore {
+	if gi == nil {
+		return nil
+	}
+	base := path.Join(gi.prefix, relDir)
+
+	var rules []gitignoreRule
+	if file, err := fsys.Open(path.Join(relDir, ".gitignore")); err == nil {
+		rules = parseIgnoreRules(file, base)
+		file.Close()
+	}
+	if len(rules) == 0 {
+		return gi
+	}
+	combined := make([]gitignoreRule, 0, len(gi.rules)+len(rules))
+	combined = append(combined, gi.rules...)
+	combined = append(combined, rules...)
+	return &GitIgnore{rules: combined, prefix: gi.prefix}
+}
+
+// Ignored reports whether relPath (slash-separated, relative to the walk
+// root) is excluded by the rules
+func (gi *GitIgnore) Ignored(relPath string, isDir bool) bool {
+	if gi == nil {
+		return false
+	}
+	full := path.Join(gi.prefix, relPath)
+
+	for i := len(gi.rules) - 1; i >= 0; i-- {
+		rule := gi.rules[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		rel := full
+		if rule.base != "" {
+			if !strings.HasPrefix(full, rule.base+"/") {
+				continue
+			}
+			rel = full[len(rule.base)+1:]
+		}
+
+		target := rel
+		if !rule.anchored {
+			target = path.Base(rel)
+		}
+		if rule.pattern.Match(target) {
+			return !rule.negate
+		}
+	}
+	return false
+}
+
+// parseIgnoreLine converts one line of an ignore file into a rule
+func parseIgnoreLine(line string, base string) (gitignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+
+	// Trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || line[0] == '#' {
+		return gitignoreRule{}, false
+	}
+
+	rule := gitignoreRule{base: base}
+	if line[0] == '!' {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return gitignoreRule{}, false
+	}
+
+	// A slash anywhere but the end anchors the pattern to the file's directory
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	pattern, err := glob.Compile(line)
+	if err != nil {
+		return gitignoreRule{}, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
+
+func readIgnoreFile(filename string, base string) []gitignoreRule {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	return parseIgnoreRules(file, base)
+}
+
+func parseIgnoreRules(reader io.Reader, base string) []gitignoreRule {
+	var rules []gitignoreRule
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(scanner.Text(), base); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// findRepoRoot returns the closest ancestor of dir that contains a .git entry
+func findRepoRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// globalExcludesFile returns the path of the user's global excludes file,
+// honouring core.excludesFile in ~/.gitconfig
+func globalExcludesFile() string {
+	home, _ := os.UserHomeDir()
+
+	if home != "" {
+		if file := readExcludesSetting(filepath.Join(home, ".gitconfig")); file != "" {
+			if strings.HasPrefix(file, "~/") {
+				file = filepath.Join(home, file[2:])
+			}
+			return file
+		}
+	}
+
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "git", "ignore")
+	}
+	if home != "" {
+		return filepath.Join(home, ".config", "git", "ignore")
+	}
+	return ""
+}
+
+// readExcludesSetting extracts core.excludesFile from a git config file
+func readExcludesSetting(configFile string) string {
+	file, err := os.Open(configFile)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	inCore := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inCore = strings.EqualFold(strings.Trim(line, "[] \t"), "core")
+			continue
+		}
+		if !inCore {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "excludesfile") {
+			return strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return ""
+}
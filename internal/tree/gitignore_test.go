@@ -0,0 +1,120 @@
+package tree
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGitIgnoreIgnored(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"basename", "*.log", "debug.log", false, true},
+		{"basename nested", "*.log", "a/b/debug.log", false, true},
+		{"no match", "*.log", "main.go", false, false},
+		{"comment", "# *.go", "main.go", false, false},
+		{"escaped hash", `\#notes`, "#notes", false, true},
+		{"trailing spaces", "*.log   ", "debug.log", false, true},
+
+		{"negation", "*.log\n!keep.log", "keep.log", false, false},
+		{"negation other", "*.log\n!keep.log", "debug.log", false, true},
+		{"last match wins", "!keep.log\n*.log", "keep.log", false, true},
+		{"escaped bang", `\!important`, "!important", false, true},
+
+		{"dir only dir", "build/", "build", true, true},
+		{"dir only file", "build/", "build", false, false},
+		{"dir only nested", "build/", "src/build", true, true},
+
+		{"anchored", "/build", "build", true, true},
+		{"anchored nested", "/build", "src/build", true, false},
+		{"middle slash", "doc/*.txt", "doc/a.txt", false, true},
+		{"middle slash nested", "doc/*.txt", "src/doc/a.txt", false, false},
+		{"middle slash deeper", "doc/*.txt", "doc/x/a.txt", false, false},
+
+		{"leading doublestar", "**/logs", "logs", true, true},
+		{"leading doublestar nested", "**/logs", "a/b/logs", true, true},
+		{"trailing doublestar", "abc/**", "abc/x/y", false, true},
+		{"trailing doublestar dir", "abc/**", "abc", true, false},
+		{"middle doublestar", "a/**/b", "a/b", false, true},
+		{"middle doublestar nested", "a/**/b", "a/x/y/b", false, true},
+
+		// Ignore files use fnmatch syntax, where braces are literal
+		{"braces literal", "{a,b}.txt", "{a,b}.txt", false, true},
+		{"braces no expansion", "{a,b}.txt", "a.txt", false, false},
+	}
+
+	for _, tt := range tests {
+		gi := &GitIgnore{rules: parseIgnoreRules(strings.NewReader(tt.rules), "")}
+		if got := gi.Ignored(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%s: rules %q: Ignored(%q, %v) = %v, want %v", tt.name, tt.rules, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestGitIgnoreEnter(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sub/.gitignore":      {Data: []byte("*.tmp\n!root.log\n/only-here\n")},
+		"sub/deep/.gitignore": {Data: []byte("!keep.tmp\n")},
+	}
+	root := &GitIgnore{rules: parseIgnoreRules(strings.NewReader("*.log\n"), "")}
+	sub := root.Enter(fsys, "sub")
+	deep := sub.Enter(fsys, "sub/deep")
+	other := root.Enter(fsys, "other")
+
+	tests := []struct {
+		name string
+		gi   *GitIgnore
+		path string
+		want bool
+	}{
+		{"outer rule in sub", sub, "sub/a.log", true},
+		{"nested rule", sub, "sub/a.tmp", true},
+		{"nested rule below", sub, "sub/x/a.tmp", true},
+		{"nested negation", sub, "sub/root.log", false},
+		{"nested anchored", sub, "sub/only-here", true},
+		{"nested anchored deeper", sub, "sub/x/only-here", false},
+		{"deeper negation", deep, "sub/deep/keep.tmp", false},
+		{"deeper inherits", deep, "sub/deep/other.tmp", true},
+		{"sibling unaffected", other, "other/a.tmp", false},
+		{"sibling keeps outer rule", other, "other/a.log", true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.gi.Ignored(tt.path, false); got != tt.want {
+			t.Errorf("%s: Ignored(%q) = %v, want %v", tt.name, tt.path, got, tt.want)
+		}
+	}
+
+	if other != root {
+		t.Errorf("Enter of a directory without .gitignore returned new rules")
+	}
+}
+
+func TestGitIgnorePrefix(t *testing.T) {
+	// Rules from the repository root apply to a walk started in a subdirectory
+	gi := &GitIgnore{
+		rules:  parseIgnoreRules(strings.NewReader("/src/gen\nsrc/*.o\n"), ""),
+		prefix: "src",
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"gen", true},
+		{"a.o", true},
+		{"x/gen", false},
+		{"x/a.o", false},
+	}
+
+	for _, tt := range tests {
+		if got := gi.Ignored(tt.path, false); got != tt.want {
+			t.Errorf("Ignored(%q) with prefix %q = %v, want %v", tt.path, gi.prefix, got, tt.want)
+		}
+	}
+}
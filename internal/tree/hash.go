@@ -0,0 +1,228 @@
+package tree
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// CheckHashAlgorithm reports whether HashTree supports the named algorithm
+func CheckHashAlgorithm(algorithm string) error {
+	_, err := newHasher(algorithm)
+	return err
+}
+
+// newHasher returns a constructor for the named hash algorithm
+func newHasher(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case "sha256":
+		return sha256.New, nil
+	case "sha1":
+		return sha1.New, nil
+	case "md5":
+		return md5.New, nil
+	case "crc32":
+		return func() hash.Hash { return crc32.NewIEEE() }, nil
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm %q (use sha256, sha1, md5 or crc32)", algorithm)
+	}
+}
+
+// HashTree sets Hash on every node of a tree walked from fsys. Files are
+// hashed by content, with up to jobs files read in parallel (0 = number of
+// CPUs). Each directory gets a Merkle hash of the names, types and hashes
+// of its children, so identical subtrees hash the same whatever they are
+// called. The hash of a directory covers the entries listed in the tree,
+// after depth limits and filters.
+//
+// Files that cannot be read get an error instead of a hash. Directories
+// that were not expanded (loops, mount points, unfollowed links) and
+// archive members get no hash. If ctx ends early, hashing stops and the
+// root is marked Incomplete. Files done are counted in progress, which may
+// be nil.
+func HashTree(ctx context.Context, fsys fs.FS, root *Node, algorithm string, jobs int, progress *Progress) error {
+	newHash, err := newHasher(algorithm)
+	if err != nil {
+		return err
+	}
+
+	progress.startPhase("hashing")
+	hashFiles(ctx, fsys, newHash, jobs, progress, func(files chan<- *Node) {
+		feedFiles(ctx, root, files)
+	})
+
+	if ctx.Err() != nil {
+		root.Incomplete = true
+		return nil
+	}
+	merkleHash(root, newHash(), func(dir *Node, digest string, _ bool) {
+		dir.Hash = digest
+	})
+	return nil
+}
+
+// HashPath hashes a tree that was walked from rootPath on the OS filesystem
+func HashPath(ctx context.Context, rootPath string, root *Node, algorithm string, jobs int, progress *Progress) error {
+	absPath, err := filepath.Abs(rootPath)
+	if err != nil {
+		return err
+	}
+	return HashTree(ctx, os.DirFS(absPath), root, algorithm, jobs, progress)
+}
+
+// hashFiles hashes every file that feed sends, reading up to jobs files in
+// parallel (0 = number of CPUs). It returns once all of them are done, or
+// soon after ctx ends.
+func hashFiles(ctx context.Context, fsys fs.FS, newHash func() hash.Hash, jobs int, progress *Progress, feed func(files chan<- *Node)) {
+	processFiles(jobs, progress, feed, func() func(*Node) {
+		h := newHash()
+		buf := make([]byte, 64*1024)
+		return func(node *Node) {
+			hashFile(ctx, fsys, node, h, buf)
+		}
+	})
+}
+
+// processFiles runs up to jobs workers (0 = number of CPUs) on the files
+// that feed sends. Each worker gets its own function from newWorker, so it
+// can keep buffers between files. Files done are counted in progress,
+// which may be nil. It returns once all files are done.
+func processFiles(jobs int, progress *Progress, feed func(files chan<- *Node), newWorker func() func(*Node)) {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+
+	files := make(chan *Node)
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work := newWorker()
+			for node := range files {
+				work(node)
+				progress.fileDone(node)
+			}
+		}()
+	}
+
+	feed(files)
+	close(files)
+	wg.Wait()
+}
+
+// feedFiles sends every regular file below node, and every link to one,
+// to files, stopping early if ctx ends. Devices, pipes and links to them
+// are skipped, since reading them may never end.
+func feedFiles(ctx context.Context, node *Node, files chan<- *Node) bool {
+	if !node.IsDir {
+		if node.Err != nil || !node.isRegular() {
+			return true
+		}
+		select {
+		case files <- node:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// Archive members cannot be opened through fsys
+	if node.IsArchive {
+		return true
+	}
+	for _, child := range node.Children {
+		if !feedFiles(ctx, child, files) {
+			return false
+		}
+	}
+	return true
+}
+
+// hashFile reads a file through fsys and stores its digest on node. If ctx
+// ends first, the node is left without a digest.
+func hashFile(ctx context.Context, fsys fs.FS, node *Node, h hash.Hash, buf []byte) {
+	file, err := fsys.Open(node.RelPath())
+	if err != nil {
+		node.Err = newWalkError("hashing", err)
+		return
+	}
+	defer file.Close()
+
+	h.Reset()
+	if _, err := io.CopyBuffer(h, contextReader{ctx, file}, buf); err != nil {
+		if ctx.Err() == nil {
+			node.Err = newWalkError("hashing", err)
+		}
+		return
+	}
+	node.Hash = hex.EncodeToString(h.Sum(nil))
+}
+
+// contextReader stops reading once ctx ends, checking between chunks
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// merkleHash computes the Merkle hash of every directory below node and
+// passes it to store, along with whether every file below the directory
+// has a digest. Children are hashed in name order, so the result does not
+// depend on the sort order used for display. It returns the digest of
+// node and whether it is complete. Directories whose entries were not
+// listed have no digest.
+func merkleHash(node *Node, h hash.Hash, store func(dir *Node, digest string, complete bool)) (string, bool) {
+	if !node.IsDir || node.IsArchive {
+		return node.Hash, node.Hash != ""
+	}
+	if node.Unlisted() {
+		return "", false
+	}
+
+	children := make([]*Node, len(node.Children))
+	copy(children, node.Children)
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Name < children[j].Name
+	})
+
+	complete := true
+	digests := make([]string, len(children))
+	for i, child := range children {
+		digest, ok := merkleHash(child, h, store)
+		digests[i] = digest
+		complete = complete && ok
+	}
+
+	// Each entry is its kind, name and digest, so that renaming or
+	// replacing a file with a directory changes the hash
+	h.Reset()
+	for i, child := range children {
+		kind := "f"
+		if child.IsDir && !child.IsArchive {
+			kind = "d"
+		}
+		fmt.Fprintf(h, "%s %s\x00%s\n", kind, child.Name, digests[i])
+	}
+	digest := hex.EncodeToString(h.Sum(nil))
+	store(node, digest, complete)
+	return digest, complete
+}
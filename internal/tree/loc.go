@@ -0,0 +1,344 @@
+package tree
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LineCount holds the line counts of a file, or of all files below a
+// directory
+type LineCount struct {
+	Total   int64
+	Blank   int64
+	Comment int64
+}
+
+// Code returns the number of lines that are neither blank nor comments
+func (c LineCount) Code() int64 {
+	return c.Total - c.Blank - c.Comment
+}
+
+func (c *LineCount) add(other LineCount) {
+	c.Total += other.Total
+	c.Blank += other.Blank
+	c.Comment += other.Comment
+}
+
+// language describes how comments are written in a programming language
+type language struct {
+	name       string
+	line       []string // line comment markers
+	blockStart string
+	blockEnd   string
+}
+
+// OtherLanguage is the language of text files that are not recognised.
+// Only their total and blank lines are counted.
+const OtherLanguage = "Other"
+
+var (
+	cStyle     = func(name string) *language { return &language{name, []string{"//"}, "/*", "*/"} }
+	hashStyle  = func(name string) *language { return &language{name: name, line: []string{"#"}} }
+	dashStyle  = func(name string) *language { return &language{name, []string{"--"}, "/*", "*/"} }
+	xmlStyle   = func(name string) *language { return &language{name: name, blockStart: "<!--", blockEnd: "-->"} }
+	plainStyle = func(name string) *language { return &language{name: name} }
+)
+
+// languagesByExt maps lower-case file extensions to languages
+var languagesByExt = map[string]*language{
+	".go":     cStyle("Go"),
+	".c":      cStyle("C"),
+	".h":      cStyle("C"),
+	".cc":     cStyle("C++"),
+	".cpp":    cStyle("C++"),
+	".cxx":    cStyle("C++"),
+	".hpp":    cStyle("C++"),
+	".cs":     cStyle("C#"),
+	".java":   cStyle("Java"),
+	".kt":     cStyle("Kotlin"),
+	".scala":  cStyle("Scala"),
+	".groovy": cStyle("Groovy"),
+	".js":     cStyle("JavaScript"),
+	".mjs":    cStyle("JavaScript"),
+	".cjs":    cStyle("JavaScript"),
+	".jsx":    cStyle("JavaScript"),
+	".ts":     cStyle("TypeScript"),
+	".tsx":    cStyle("TypeScript"),
+	".rs":     cStyle("Rust"),
+	".swift":  cStyle("Swift"),
+	".dart":   cStyle("Dart"),
+	".zig":    cStyle("Zig"),
+	".m":      cStyle("Objective-C"),
+	".proto":  cStyle("Protocol Buffers"),
+	".css":    {name: "CSS", blockStart: "/*", blockEnd: "*/"},
+	".scss":   cStyle("SCSS"),
+	".less":   cStyle("Less"),
+	".php":    {"PHP", []string{"//", "#"}, "/*", "*/"},
+	".py":     hashStyle("Python"),
+	".rb":     {"Ruby", []string{"#"}, "=begin", "=end"},
+	".pl":     hashStyle("Perl"),
+	".sh":     hashStyle("Shell"),
+	".bash":   hashStyle("Shell"),
+	".zsh":    hashStyle("Shell"),
+	".fish":   hashStyle("Shell"),
+	".r":      hashStyle("R"),
+	".ex":     hashStyle("Elixir"),
+	".exs":    hashStyle("Elixir"),
+	".nim":    hashStyle("Nim"),
+	".jl":     hashStyle("Julia"),
+	".tf":     {"Terraform", []string{"#", "//"}, "/*", "*/"},
+	".yaml":   hashStyle("YAML"),
+	".yml":    hashStyle("YAML"),
+	".toml":   hashStyle("TOML"),
+	".ini":    {name: "INI", line: []string{";", "#"}},
+	".mk":     hashStyle("Makefile"),
+	".sql":    dashStyle("SQL"),
+	".lua":    {"Lua", []string{"--"}, "--[[", "]]"},
+	".hs":     {"Haskell", []string{"--"}, "{-", "-}"},
+	".erl":    {name: "Erlang", line: []string{"%"}},
+	".clj":    {name: "Clojure", line: []string{";"}},
+	".lisp":   {name: "Lisp", line: []string{";"}},
+	".el":     {name: "Emacs Lisp", line: []string{";"}},
+	".vim":    {name: "Vim script", line: []string{"\""}},
+	".html":   xmlStyle("HTML"),
+	".htm":    xmlStyle("HTML"),
+	".xml":    xmlStyle("XML"),
+	".svg":    xmlStyle("SVG"),
+	".vue":    xmlStyle("Vue"),
+	".md":     plainStyle("Markdown"),
+	".rst":    plainStyle("reStructuredText"),
+	".json":   plainStyle("JSON"),
+	".csv":    plainStyle("CSV"),
+	".txt":    plainStyle("Text"),
+}
+
+// languagesByName maps well-known file names to languages
+var languagesByName = map[string]*language{
+	"Makefile":       hashStyle("Makefile"),
+	"GNUmakefile":    hashStyle("Makefile"),
+	"Dockerfile":     hashStyle("Dockerfile"),
+	"Containerfile":  hashStyle("Dockerfile"),
+	"Jenkinsfile":    cStyle("Groovy"),
+	"Vagrantfile":    hashStyle("Ruby"),
+	"Rakefile":       hashStyle("Ruby"),
+	"Gemfile":        hashStyle("Ruby"),
+	"go.mod":         cStyle("Go module"),
+	"CMakeLists.txt": hashStyle("CMake"),
+}
+
+// languageOf returns the language of a file from its name, or nil
+func languageOf(name string) *language {
+	if lang, ok := languagesByName[name]; ok {
+		return lang
+	}
+	return languagesByExt[strings.ToLower(path.Ext(name))]
+}
+
+// CountLines sets Lines and Language on every text file of a tree walked
+// from fsys, reading up to jobs files in parallel (0 = number of CPUs).
+// Blank and comment lines are told apart for known languages; other text
+// files count as OtherLanguage, and binary files are skipped. Each
+// directory gets the sum of the files below it, and the language with the
+// most lines as its Language.
+//
+// If ctx ends early, counting stops and the root is marked Incomplete.
+// Files done are counted in progress, which may be nil.
+func CountLines(ctx context.Context, fsys fs.FS, root *Node, jobs int, progress *Progress) {
+	progress.startPhase("counting lines")
+	processFiles(jobs, progress, func(files chan<- *Node) {
+		feedTextFiles(ctx, root, files)
+	}, func() func(*Node) {
+		buf := make([]byte, 64*1024)
+		return func(node *Node) {
+			countFile(fsys, node, buf)
+		}
+	})
+
+	if ctx.Err() != nil {
+		root.Incomplete = true
+	}
+	rollUpLines(root)
+}
+
+// CountLinesPath counts the lines of a tree walked from rootPath on the OS
+// filesystem
+func CountLinesPath(ctx context.Context, rootPath string, root *Node, jobs int, progress *Progress) error {
+	absPath, err := filepath.Abs(rootPath)
+	if err != nil {
+		return err
+	}
+	CountLines(ctx, os.DirFS(absPath), root, jobs, progress)
+	return nil
+}
+
+// feedTextFiles sends the regular files below node that may hold text,
+// stopping early if ctx ends
+func feedTextFiles(ctx context.Context, node *Node, files chan<- *Node) bool {
+	if node.IsArchive || node.IsSymlink || node.Err != nil {
+		return true
+	}
+	if !node.IsDir {
+		if !node.Mode.IsRegular() {
+			return true
+		}
+		select {
+		case files <- node:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for _, child := range node.Children {
+		if !feedTextFiles(ctx, child, files) {
+			return false
+		}
+	}
+	return true
+}
+
+// countFile counts the lines of one file. Files that cannot be read, or
+// that contain NUL bytes, are left without counts.
+func countFile(fsys fs.FS, node *Node, buf []byte) {
+	file, err := fsys.Open(node.RelPath())
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	lang := languageOf(node.Name)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(buf, 1024*1024)
+
+	var count LineCount
+	inBlock := false
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if bytes.IndexByte(line, 0) >= 0 {
+			return
+		}
+		count.Total++
+
+		text := strings.TrimSpace(string(line))
+		switch {
+		case text == "":
+			count.Blank++
+		case lang == nil:
+		case inBlock:
+			count.Comment++
+			inBlock = !strings.Contains(text, lang.blockEnd)
+		case lang.blockStart != "" && strings.HasPrefix(text, lang.blockStart):
+			count.Comment++
+			rest := text[len(lang.blockStart):]
+			inBlock = !strings.Contains(rest, lang.blockEnd)
+		case hasAnyPrefix(text, lang.line):
+			count.Comment++
+		}
+	}
+	if scanner.Err() != nil {
+		return
+	}
+
+	node.Lines = count
+	node.Language = OtherLanguage
+	if lang != nil {
+		node.Language = lang.name
+	}
+}
+
+// hasAnyPrefix reports whether s starts with one of the prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// rollUpLines sets the line counts and main language of every directory
+// below node, and returns the total lines per language
+func rollUpLines(node *Node) map[string]int64 {
+	if !node.IsDir || node.IsArchive {
+		if node.Language == "" {
+			return nil
+		}
+		return map[string]int64{node.Language: node.Lines.Total}
+	}
+
+	byLanguage := make(map[string]int64)
+	node.Lines = LineCount{}
+	for _, child := range node.Children {
+		for lang, lines := range rollUpLines(child) {
+			byLanguage[lang] += lines
+		}
+		node.Lines.add(child.Lines)
+	}
+
+	node.Language = mainLanguage(byLanguage)
+	return byLanguage
+}
+
+// mainLanguage returns the language with the most lines, preferring
+// recognised languages over OtherLanguage
+func mainLanguage(byLanguage map[string]int64) string {
+	best := ""
+	for lang, lines := range byLanguage {
+		if best == "" || lang != OtherLanguage && (best == OtherLanguage || lines > byLanguage[best] ||
+			lines == byLanguage[best] && lang < best) {
+			best = lang
+		}
+	}
+	return best
+}
+
+// LanguageStats summarises the files of one language
+type LanguageStats struct {
+	Language string
+	Files    int
+	Lines    LineCount
+}
+
+// LanguageSummary returns the line counts of the files in a tree grouped
+// by language, most lines first
+func LanguageSummary(root *Node) []LanguageStats {
+	byLanguage := make(map[string]*LanguageStats)
+	var collect func(node *Node)
+	collect = func(node *Node) {
+		if !node.IsDir || node.IsArchive {
+			if node.Language == "" {
+				return
+			}
+			summary, ok := byLanguage[node.Language]
+			if !ok {
+				summary = &LanguageStats{Language: node.Language}
+				byLanguage[node.Language] = summary
+			}
+			summary.Files++
+			summary.Lines.add(node.Lines)
+			return
+		}
+		for _, child := range node.Children {
+			collect(child)
+		}
+	}
+	collect(root)
+
+	list := make([]LanguageStats, 0, len(byLanguage))
+	for _, summary := range byLanguage {
+		list = append(list, *summary)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Lines.Total != list[j].Lines.Total {
+			return list[i].Lines.Total > list[j].Lines.Total
+		}
+		return list[i].Language < list[j].Language
+	})
+	return list
+}
@@ -1,27 +1,65 @@
 package tree
 
 import (
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"time"
+
+	"dtree/internal/stats"
 )
 
 // Node represents a file or directory in the tree
 type Node struct {
-	Name     string
-	Path     string
-	IsDir    bool
+	Name      string
+	Path      string
+	IsDir     bool
 	IsSymlink bool
-	Children []*Node
-	Parent   *Node
-	Size     int64
-	ModTime  time.Time
-	Mode     os.FileMode
+	Children  []*Node
+	Parent    *Node
+	Size      int64
+	ModTime   time.Time
+	Mode      os.FileMode
+	IsLoop    bool  // symlink back to a directory already being walked
+	IsBroken  bool  // symlink whose target does not exist
+	Err       error // set when the entry could not be read during the walk
+
+	IsMountPoint bool // directory on another filesystem that was not entered
+	IsArchive    bool // archive file whose members are listed as children
+	DepthLimited bool // directory whose entries are below the depth limit
+
+	DiskSize int64  // bytes allocated on disk
+	Device   uint64 // device number, when known
+	Inode    uint64 // inode number, when known
+	Links    uint64 // number of hard links, when known
+
+	UID   uint32 // owning user ID, when known
+	GID   uint32 // owning group ID, when known
+	Owner string // owning user name once looked up (see OwnerName)
+	Group string // owning group name once looked up (see GroupName)
+	Hash  string // hex digest of the contents, or Merkle hash of a directory
+	MIME  string // MIME type sniffed from the contents, with --detect
+	Kind  string // content kind sniffed with --detect, one of Kinds
+
+	Lines    LineCount // line counts of a text file, or the sum below a directory
+	Language string    // language of a text file, or the main one below a directory
+
+	EntryCount int   // entries of a directory left unlisted because of the file limit
+	EntryBytes int64 // total size of the files among those entries
+	Truncated  bool  // set on the root when the walk stopped at the entry limit
+	Incomplete bool  // set on the root when the walk was cancelled before finishing
+
+	Label  string     // extra note shown after the name, such as a duplicate marker
+	dupe   *DupeGroup // duplicate group found by FindDuplicates, if any
+	hasIDs bool       // UID and GID were read from the file system
+
+	linkMode os.FileMode // mode of a symlink's target, when it exists
 }
 
 // NewNode creates a new Node from file info
 func NewNode(path string, info os.FileInfo) *Node {
-	return &Node{
+	node := &Node{
 		Name:      info.Name(),
 		Path:      path,
 		IsDir:     info.IsDir(),
@@ -31,6 +69,13 @@ func NewNode(path string, info os.FileInfo) *Node {
 		Mode:      info.Mode(),
 		Children:  make([]*Node, 0),
 	}
+	fillSysInfo(node, info)
+	return node
+}
+
+// Permissions returns the mode in ls -l notation, e.g. "-rw-r--r--"
+func (n *Node) Permissions() string {
+	return FormatPermissions(n.Mode)
 }
 
 // AddChild adds a child node to this node
@@ -55,8 +100,82 @@ func (n *Node) GetDepth() int {
 	return depth
 }
 
+// Unlisted reports whether the entries of a directory were left out of
+// the tree because it could not be read, exceeded the file limit, is below
+// the depth limit, is a mount point that was not entered, loops back to
+// an ancestor or is a symlink that was not followed. Such a directory is
+// not known to be empty even though it has no children.
+func (n *Node) Unlisted() bool {
+	return n.Err != nil || n.EntryCount > 0 || n.DepthLimited || n.IsMountPoint || n.IsLoop ||
+		n.IsDir && n.IsSymlink && len(n.Children) == 0
+}
+
+// isRegular reports whether the node is a regular file or a symlink to one
+func (n *Node) isRegular() bool {
+	if n.IsSymlink {
+		return !n.IsBroken && n.linkMode.IsRegular()
+	}
+	return n.Mode.IsRegular()
+}
+
+// Annotation returns a bracketed note explaining why the node was not
+// expanded, or "" if there is nothing to report
+func (n *Node) Annotation() string {
+	if n.Err != nil {
+		return "[error " + n.Err.Error() + "]"
+	}
+	if n.Incomplete {
+		return "[incomplete]"
+	}
+	if n.IsLoop {
+		return "[recursive, not followed]"
+	}
+	if n.IsMountPoint {
+		return "[mount point]"
+	}
+	if n.EntryCount > 0 {
+		return fmt.Sprintf("[%d entries exceeds filelimit, %s]", n.EntryCount, stats.FormatSize(n.EntryBytes))
+	}
+	return n.Label
+}
+
+// Annotate appends the node's annotation, if any, to text
+func Annotate(text string, node *Node) string {
+	if note := node.Annotation(); note != "" {
+		return text + "  " + note
+	}
+	return text
+}
+
+// ErrorCount returns the number of nodes in the subtree that failed to read
+func (n *Node) ErrorCount() int {
+	count := 0
+	if n.Err != nil {
+		count++
+	}
+	for _, child := range n.Children {
+		count += child.ErrorCount()
+	}
+	return count
+}
+
+// RelPath returns the slash-separated path of the node relative to the
+// root of its tree, or "." for the root itself
+func (n *Node) RelPath() string {
+	if n.Parent == nil {
+		return "."
+	}
+	var parts []string
+	for node := n; node.Parent != nil; node = node.Parent {
+		parts = append(parts, node.Name)
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return path.Join(parts...)
+}
+
 // GetFullPath returns the full path of the node
 func (n *Node) GetFullPath() string {
 	return filepath.Join(n.Path, n.Name)
 }
-
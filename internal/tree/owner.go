@@ -0,0 +1,72 @@
+package tree
+
+import (
+	"os/user"
+	"strconv"
+	"sync"
+)
+
+// nameCache remembers user and group names by numeric ID, as every file
+// in a tree usually belongs to a handful of owners
+type nameCache struct {
+	mu     sync.Mutex
+	names  map[uint32]string
+	lookup func(id string) (string, error)
+}
+
+var (
+	userNames = &nameCache{lookup: func(id string) (string, error) {
+		u, err := user.LookupId(id)
+		if err != nil {
+			return "", err
+		}
+		return u.Username, nil
+	}}
+	groupNames = &nameCache{lookup: func(id string) (string, error) {
+		g, err := user.LookupGroupId(id)
+		if err != nil {
+			return "", err
+		}
+		return g.Name, nil
+	}}
+)
+
+// name returns the name for id, or id itself as a number if it has no name
+func (c *nameCache) name(id uint32) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if name, ok := c.names[id]; ok {
+		return name
+	}
+	if c.names == nil {
+		c.names = make(map[uint32]string)
+	}
+
+	name, err := c.lookup(strconv.FormatUint(uint64(id), 10))
+	if err != nil || name == "" {
+		name = strconv.FormatUint(uint64(id), 10)
+	}
+	c.names[id] = name
+	return name
+}
+
+// OwnerName returns the name of the owning user, looking it up on first
+// use, or the UID as a number if it has no name. It returns "" when the
+// owner is not known.
+func (n *Node) OwnerName() string {
+	if n.Owner == "" && n.hasIDs {
+		n.Owner = userNames.name(n.UID)
+	}
+	return n.Owner
+}
+
+// GroupName returns the name of the owning group, looking it up on first
+// use, or the GID as a number if it has no name. It returns "" when the
+// group is not known.
+func (n *Node) GroupName() string {
+	if n.Group == "" && n.hasIDs {
+		n.Group = groupNames.name(n.GID)
+	}
+	return n.Group
+}
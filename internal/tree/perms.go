@@ -0,0 +1,108 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+)
+
+// permBits lists the permission bits in the order ls prints them
+var permBits = [9]struct {
+	bit  os.FileMode
+	char byte
+}{
+	{0400, 'r'}, {0200, 'w'}, {0100, 'x'},
+	{0040, 'r'}, {0020, 'w'}, {0010, 'x'},
+	{0004, 'r'}, {0002, 'w'}, {0001, 'x'},
+}
+
+// FormatPermissions formats mode the way ls -l does, e.g. "drwxr-xr-x"
+func FormatPermissions(mode os.FileMode) string {
+	buf := []byte("----------")
+
+	switch {
+	case mode&os.ModeDir != 0:
+		buf[0] = 'd'
+	case mode&os.ModeSymlink != 0:
+		buf[0] = 'l'
+	case mode&os.ModeNamedPipe != 0:
+		buf[0] = 'p'
+	case mode&os.ModeSocket != 0:
+		buf[0] = 's'
+	case mode&os.ModeCharDevice != 0:
+		buf[0] = 'c'
+	case mode&os.ModeDevice != 0:
+		buf[0] = 'b'
+	}
+
+	for i, perm := range permBits {
+		if mode&perm.bit != 0 {
+			buf[i+1] = perm.char
+		}
+	}
+
+	// Special bits replace the execute bit they belong to
+	special := func(index int, set bool, lower, upper byte) {
+		if !set {
+			return
+		}
+		if buf[index] == 'x' {
+			buf[index] = lower
+		} else {
+			buf[index] = upper
+		}
+	}
+	special(3, mode&os.ModeSetuid != 0, 's', 'S')
+	special(6, mode&os.ModeSetgid != 0, 's', 'S')
+	special(9, mode&os.ModeSticky != 0, 't', 'T')
+
+	return string(buf)
+}
+
+// ParsePermissions is the inverse of FormatPermissions
+func ParsePermissions(s string) (os.FileMode, error) {
+	if len(s) != 10 {
+		return 0, fmt.Errorf("invalid permissions %q", s)
+	}
+
+	var mode os.FileMode
+	switch s[0] {
+	case '-':
+	case 'd':
+		mode |= os.ModeDir
+	case 'l':
+		mode |= os.ModeSymlink
+	case 'p':
+		mode |= os.ModeNamedPipe
+	case 's':
+		mode |= os.ModeSocket
+	case 'c':
+		mode |= os.ModeDevice | os.ModeCharDevice
+	case 'b':
+		mode |= os.ModeDevice
+	default:
+		return 0, fmt.Errorf("invalid permissions %q", s)
+	}
+
+	for i, perm := range permBits {
+		c := s[i+1]
+		switch {
+		case c == perm.char:
+			mode |= perm.bit
+		case c == '-':
+		case i == 2 && (c == 's' || c == 'S'):
+			mode |= os.ModeSetuid
+		case i == 5 && (c == 's' || c == 'S'):
+			mode |= os.ModeSetgid
+		case i == 8 && (c == 't' || c == 'T'):
+			mode |= os.ModeSticky
+		default:
+			return 0, fmt.Errorf("invalid permissions %q", s)
+		}
+		// Lower-case special bits imply the execute bit
+		if c == 's' || c == 't' {
+			mode |= perm.bit
+		}
+	}
+
+	return mode, nil
+}
@@ -0,0 +1,95 @@
+package tree
+
+import "sync/atomic"
+
+// Progress holds live counters of a running walk, and of the work done on
+// the files afterwards, such as hashing. All methods are safe to call
+// while the walk is in progress.
+type Progress struct {
+	dirs    atomic.Int64
+	files   atomic.Int64
+	bytes   atomic.Int64
+	current atomic.Pointer[string]
+
+	phase      atomic.Pointer[string]
+	phaseFiles atomic.Int64
+	phaseBytes atomic.Int64
+}
+
+// Dirs returns the number of directories read so far
+func (p *Progress) Dirs() int64 {
+	return p.dirs.Load()
+}
+
+// Files returns the number of non-directory entries seen so far
+func (p *Progress) Files() int64 {
+	return p.files.Load()
+}
+
+// Bytes returns the total size of the files seen so far
+func (p *Progress) Bytes() int64 {
+	return p.bytes.Load()
+}
+
+// Current returns the directory being read, or the file processed, most
+// recently
+func (p *Progress) Current() string {
+	if current := p.current.Load(); current != nil {
+		return *current
+	}
+	return ""
+}
+
+func (p *Progress) enterDir(path string) {
+	if p == nil {
+		return
+	}
+	p.dirs.Add(1)
+	p.current.Store(&path)
+}
+
+func (p *Progress) addEntry(node *Node) {
+	if p == nil || node.IsDir {
+		return
+	}
+	p.files.Add(1)
+	p.bytes.Add(node.Size)
+}
+
+// Phase returns the work being done on the files after the walk, such as
+// "hashing", or "" while walking
+func (p *Progress) Phase() string {
+	if phase := p.phase.Load(); phase != nil {
+		return *phase
+	}
+	return ""
+}
+
+// PhaseFiles returns the number of files done in the current phase
+func (p *Progress) PhaseFiles() int64 {
+	return p.phaseFiles.Load()
+}
+
+// PhaseBytes returns the total size of the files done in the current phase
+func (p *Progress) PhaseBytes() int64 {
+	return p.phaseBytes.Load()
+}
+
+func (p *Progress) startPhase(name string) {
+	if p == nil {
+		return
+	}
+	p.phaseFiles.Store(0)
+	p.phaseBytes.Store(0)
+	p.phase.Store(&name)
+}
+
+func (p *Progress) fileDone(node *Node) {
+	if p == nil {
+		return
+	}
+	p.phaseFiles.Add(1)
+	p.phaseBytes.Add(node.Size)
+	path := node.GetFullPath()
+	p.current.Store(&path)
+}
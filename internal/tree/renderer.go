@@ -3,93 +3,300 @@ package tree
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strings"
-)
+	"text/tabwriter"
 
-const (
-	// Box-drawing characters for tree structure
-	TreeBranch  = "├── "
-	TreeLast    = "└── "
-	TreePipe    = "│   "
-	TreeSpace   = "    "
+	"dtree/internal/stats"
 )
 
-// Renderer handles rendering the tree structure
+// Column adds a piece of metadata after the name of every node
+type Column interface {
+	// Value returns the text shown for node, or "" to show nothing
+	Value(node *Node) string
+}
+
+// Preparer is implemented by columns that need to look at the whole tree
+// before rendering, such as directory totals
+type Preparer interface {
+	Prepare(root *Node)
+}
+
+// ColumnFunc adapts a function to the Column interface
+type ColumnFunc func(node *Node) string
+
+// Value calls f(node)
+func (f ColumnFunc) Value(node *Node) string {
+	return f(node)
+}
+
+// Decorator changes how the name of a node is displayed, e.g. by coloring
+// it or adding an icon. It receives the name as decorated so far.
+type Decorator interface {
+	Decorate(node *Node, name string) string
+}
+
+// DecoratorFunc adapts a function to the Decorator interface
+type DecoratorFunc func(node *Node, name string) string
+
+// Decorate calls f(node, name)
+func (f DecoratorFunc) Decorate(node *Node, name string) string {
+	return f(node, name)
+}
+
+// Renderer handles rendering the tree structure. Names pass through the
+// decorators in the order they were added, and columns follow the name in
+// the order they were added.
 type Renderer struct {
-	writer io.Writer
+	writer     io.Writer
+	charset    Charset
+	columns    []Column
+	decorators []Decorator
+
+	sortBy    string
+	summary   bool // item counts in the header and totals in the footer
+	diskUsage bool // totals include the space allocated on disk
+	languages bool // line counts per language after the footer
 }
 
 // NewRenderer creates a new renderer
 func NewRenderer(writer io.Writer) *Renderer {
-	return &Renderer{writer: writer}
+	return &Renderer{writer: writer, charset: DefaultCharset}
+}
+
+// SetCharset changes the characters used to draw the branches
+func (r *Renderer) SetCharset(charset Charset) {
+	r.charset = charset
+}
+
+// AddColumn appends a column to every line
+func (r *Renderer) AddColumn(column Column) {
+	r.columns = append(r.columns, column)
+}
+
+// AddDecorator adds a decorator for names
+func (r *Renderer) AddDecorator(decorator Decorator) {
+	r.decorators = append(r.decorators, decorator)
+}
+
+// SetSort sorts children by name, size or date before rendering
+func (r *Renderer) SetSort(sortBy string) {
+	r.sortBy = sortBy
+}
+
+// SetSummary adds item counts and the total size to the root line, and a
+// line of totals after the tree. With diskUsage, the totals include the
+// space allocated on disk, counting hard-linked files once.
+func (r *Renderer) SetSummary(enabled, diskUsage bool) {
+	r.summary = enabled
+	r.diskUsage = diskUsage
+}
+
+// SetLanguageSummary adds a table of the line counts set by CountLines,
+// per language, after the tree
+func (r *Renderer) SetLanguageSummary(enabled bool) {
+	r.languages = enabled
 }
 
 // RenderTree renders the entire tree structure
 func (r *Renderer) RenderTree(root *Node, showRoot bool) error {
+	for _, column := range r.columns {
+		if preparer, ok := column.(Preparer); ok {
+			preparer.Prepare(root)
+		}
+	}
+
+	var fileStats *stats.FileStats
+	if r.summary {
+		fileStats = r.collectStats(root, showRoot)
+	}
+
+	r.sortNode(root)
+
 	if showRoot {
-		fmt.Fprintf(r.writer, "%s\n", root.Name)
+		// The summary stands in for the columns of the root
+		line := r.decorate(root)
+		if fileStats != nil {
+			totalItems := fileStats.TotalFiles + fileStats.TotalDirs
+			line += fmt.Sprintf(" (%d items, %s)", totalItems, r.formatTotal(fileStats))
+		} else {
+			line += r.columnText(root)
+		}
+		fmt.Fprintf(r.writer, "%s\n", Annotate(line, root))
+	}
+	r.renderNode(root, "", true, true)
+
+	if fileStats != nil {
+		fmt.Fprintf(r.writer, "\n")
+		fmt.Fprintf(r.writer, "Total: %d files, %d directories, %s",
+			fileStats.TotalFiles, fileStats.TotalDirs, r.formatTotal(fileStats))
+		if fileStats.Errors > 0 {
+			fmt.Fprintf(r.writer, ", %d errors", fileStats.Errors)
+		}
+		fmt.Fprintf(r.writer, "\n")
+	}
+
+	if r.languages {
+		r.renderLanguageSummary(root)
 	}
-	return r.renderNode(root, "", true, showRoot)
+
+	return nil
 }
 
-func (r *Renderer) renderNode(node *Node, prefix string, isLast bool, skipRoot bool) error {
+func (r *Renderer) renderNode(node *Node, prefix string, isLast bool, skipRoot bool) {
 	if !skipRoot {
 		// Print the node itself
-		connector := TreeLast
+		connector := r.charset.Last
 		if !isLast {
-			connector = TreeBranch
+			connector = r.charset.Branch
 		}
-		fmt.Fprintf(r.writer, "%s%s%s\n", prefix, connector, node.Name)
+		line := prefix + connector + r.decorate(node) + r.columnText(node)
+		fmt.Fprintf(r.writer, "%s\n", Annotate(line, node))
 	}
 
 	// Process children
 	children := node.Children
 	for i, child := range children {
 		isLastChild := i == len(children)-1
-		
+
 		// Determine prefix for child
 		childPrefix := prefix
 		if !skipRoot {
 			if isLast {
-				childPrefix += TreeSpace
+				childPrefix += r.charset.Space
 			} else {
-				childPrefix += TreePipe
+				childPrefix += r.charset.Pipe
 			}
 		}
 
-		// Render child
-		err := r.renderNode(child, childPrefix, isLastChild, false)
-		if err != nil {
-			return err
-		}
+		r.renderNode(child, childPrefix, isLastChild, false)
 	}
-
-	return nil
 }
 
 // RenderPlain renders the tree without box-drawing characters
 func (r *Renderer) RenderPlain(root *Node, showRoot bool) error {
 	if showRoot {
-		fmt.Fprintf(r.writer, "%s\n", root.Name)
+		fmt.Fprintf(r.writer, "%s\n", Annotate(r.decorate(root)+r.columnText(root), root))
 	}
-	return r.renderPlainNode(root, "", showRoot)
+	r.renderPlainNode(root, "", true)
+	return nil
 }
 
-func (r *Renderer) renderPlainNode(node *Node, prefix string, skipRoot bool) error {
+func (r *Renderer) renderPlainNode(node *Node, prefix string, skipRoot bool) {
 	if !skipRoot {
-		fmt.Fprintf(r.writer, "%s%s\n", prefix, node.Name)
+		fmt.Fprintf(r.writer, "%s%s\n", prefix, Annotate(r.decorate(node)+r.columnText(node), node))
 	}
 
 	// Process children
 	for _, child := range node.Children {
-		childPrefix := prefix + "  "
-		err := r.renderPlainNode(child, childPrefix, false)
-		if err != nil {
-			return err
+		r.renderPlainNode(child, prefix+"  ", false)
+	}
+}
+
+// decorate returns the name of node as changed by the decorators
+func (r *Renderer) decorate(node *Node) string {
+	name := node.Name
+	for _, decorator := range r.decorators {
+		name = decorator.Decorate(node, name)
+	}
+	return name
+}
+
+// columnText returns the column values of node, each preceded by two spaces
+func (r *Renderer) columnText(node *Node) string {
+	var sb strings.Builder
+	for _, column := range r.columns {
+		if value := column.Value(node); value != "" {
+			sb.WriteString("  ")
+			sb.WriteString(value)
 		}
 	}
+	return sb.String()
+}
 
-	return nil
+func (r *Renderer) collectStats(root *Node, skipRoot bool) *stats.FileStats {
+	fileStats := stats.NewStats()
+	collectNodeStats(root, fileStats, skipRoot)
+	if r.diskUsage {
+		fileStats.AddDiskUsage(calculateDiskUsage(root)[root])
+	}
+	return fileStats
+}
+
+func collectNodeStats(node *Node, fileStats *stats.FileStats, skipRoot bool) {
+	if node.Err != nil {
+		fileStats.AddError()
+	}
+	if !skipRoot {
+		if node.IsDir {
+			fileStats.AddDir()
+		} else {
+			fileStats.AddFile(node.Size, node.ModTime)
+		}
+	}
+
+	// Archive members are not on disk; the archive itself was counted
+	if node.IsArchive {
+		return
+	}
+
+	for _, child := range node.Children {
+		collectNodeStats(child, fileStats, false)
+	}
+}
+
+// formatTotal formats the total size for the header and footer
+func (r *Renderer) formatTotal(fileStats *stats.FileStats) string {
+	if r.diskUsage {
+		return fmt.Sprintf("%s apparent, %s on disk", stats.FormatSize(fileStats.TotalSize), stats.FormatSize(fileStats.DiskUsage))
+	}
+	return stats.FormatSize(fileStats.TotalSize)
+}
+
+// renderLanguageSummary prints a table of line counts per language
+func (r *Renderer) renderLanguageSummary(root *Node) {
+	summary := LanguageSummary(root)
+	if len(summary) == 0 {
+		return
+	}
+
+	fmt.Fprintf(r.writer, "\n")
+	table := tabwriter.NewWriter(r.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "Language\tFiles\tLines\tCode\tComment\tBlank\n")
+	var total LanguageStats
+	for _, lang := range summary {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%d\t%d\n", lang.Language, lang.Files,
+			lang.Lines.Total, lang.Lines.Code(), lang.Lines.Comment, lang.Lines.Blank)
+		total.Files += lang.Files
+		total.Lines.add(lang.Lines)
+	}
+	fmt.Fprintf(table, "Total\t%d\t%d\t%d\t%d\t%d\n", total.Files,
+		total.Lines.Total, total.Lines.Code(), total.Lines.Comment, total.Lines.Blank)
+	table.Flush()
+}
+
+func (r *Renderer) sortNode(node *Node) {
+	if r.sortBy == "" {
+		return
+	}
+
+	sort.Slice(node.Children, func(i, j int) bool {
+		switch r.sortBy {
+		case "size":
+			return node.Children[i].Size > node.Children[j].Size
+		case "date":
+			return node.Children[i].ModTime.After(node.Children[j].ModTime)
+		case "name":
+			fallthrough
+		default:
+			return strings.ToLower(node.Children[i].Name) < strings.ToLower(node.Children[j].Name)
+		}
+	})
+
+	// Recursively sort children
+	for _, child := range node.Children {
+		r.sortNode(child)
+	}
 }
 
 // GetTreeString returns the tree as a string
@@ -99,4 +306,3 @@ func GetTreeString(root *Node, showRoot bool) string {
 	renderer.RenderTree(root, showRoot)
 	return sb.String()
 }
-
@@ -0,0 +1,64 @@
+package tree
+
+import (
+	"fmt"
+	"io"
+)
+
+// StreamRenderer draws the tree line by line as nodes arrive from
+// StreamTree, with the same layout as Renderer
+type StreamRenderer struct {
+	writer     io.Writer
+	charset    Charset
+	decorators []Decorator
+	lasts      []bool // isLast of each ancestor, indexed by depth
+}
+
+// NewStreamRenderer creates a streaming renderer that passes names through
+// the given decorators, in order
+func NewStreamRenderer(writer io.Writer, decorators ...Decorator) *StreamRenderer {
+	return &StreamRenderer{writer: writer, charset: DefaultCharset, decorators: decorators}
+}
+
+// SetCharset changes the characters used to draw the branches
+func (r *StreamRenderer) SetCharset(charset Charset) {
+	r.charset = charset
+}
+
+// Enter prints the line for node
+func (r *StreamRenderer) Enter(node *Node, depth int, isLast bool) error {
+	name := node.Name
+	for _, decorator := range r.decorators {
+		name = decorator.Decorate(node, name)
+	}
+
+	// Keep track of which ancestors were last children to draw the pipes
+	r.lasts = append(r.lasts[:depth], isLast)
+
+	if depth == 0 {
+		_, err := fmt.Fprintf(r.writer, "%s\n", Annotate(name, node))
+		return err
+	}
+
+	prefix := ""
+	for _, last := range r.lasts[1:depth] {
+		if last {
+			prefix += r.charset.Space
+		} else {
+			prefix += r.charset.Pipe
+		}
+	}
+
+	connector := r.charset.Last
+	if !isLast {
+		connector = r.charset.Branch
+	}
+
+	_, err := fmt.Fprintf(r.writer, "%s%s%s\n", prefix, connector, Annotate(name, node))
+	return err
+}
+
+// Leave does nothing; lines are complete once entered
+func (r *StreamRenderer) Leave(node *Node, depth int) error {
+	return nil
+}
@@ -0,0 +1,222 @@
+package tree
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"dtree/internal/stats"
+)
+
+// Visitor receives the nodes of a streamed walk in depth-first order
+type Visitor interface {
+	// Enter is called for each node before any of its children. depth is 0
+	// for the root, and isLast reports whether the node is the last child
+	// of its parent.
+	Enter(node *Node, depth int, isLast bool) error
+	// Leave is called once all children of the node have been visited
+	Leave(node *Node, depth int) error
+}
+
+// streamBatch is the number of entries read from a directory at a time
+const streamBatch = 256
+
+// StreamTree walks fsys like WalkTree but hands every node to visitor
+// as soon as it is known instead of building the whole tree first. Nodes
+// are not kept once visited, and directories are read a batch of entries
+// at a time, so memory grows with the depth of the tree rather than with
+// its size or that of its directories. Entries therefore come in the
+// order the file system returns them, not sorted by name. With a file
+// limit, up to that many entries of a directory are held to find out
+// whether it is exceeded.
+//
+// Nodes passed to the visitor have their Parent set but are never added to
+// the parent's Children. Options that need the complete tree (Filter and
+// Prune) are not applied, and directories are read one at a time. If ctx
+// ends early the walk stops, and the root is marked Incomplete before
+// the visitor leaves it.
+func StreamTree(ctx context.Context, fsys fs.FS, options WalkerOptions, visitor Visitor) error {
+	root, w, err := newWalker(ctx, fsys, options)
+	if err != nil {
+		return err
+	}
+	rootDir := w.rootState(root)
+	return w.streamNode(root, &rootDir, 0, true, visitor)
+}
+
+// StreamPath streams a directory on disk
+func StreamPath(ctx context.Context, rootPath string, options WalkerOptions, visitor Visitor) error {
+	absPath, err := filepath.Abs(rootPath)
+	if err != nil {
+		return err
+	}
+	options.RootPath = absPath
+	return StreamTree(ctx, os.DirFS(absPath), options, visitor)
+}
+
+// streamNode reports node and, if sub is set, the contents of its directory
+func (w *walker) streamNode(node *Node, sub *dirState, depth int, isLast bool, visitor Visitor) error {
+	var entries *dirStream
+	if sub != nil {
+		// Open before entering so that read errors show on the node itself
+		entries = w.openDirectory(*sub)
+	}
+	if entries != nil {
+		defer entries.file.Close()
+	}
+
+	if err := visitor.Enter(node, depth, isLast); err != nil {
+		return err
+	}
+
+	// Archive members are built in memory up front
+	for i, child := range node.Children {
+		if err := w.streamNode(child, nil, depth+1, i == len(node.Children)-1, visitor); err != nil {
+			return err
+		}
+	}
+
+	var dirStats *stats.FileStats
+	if w.options.Stats != nil {
+		dirStats = stats.NewStats()
+	}
+
+	// Hold back one child until the next one is known, so that the last
+	// child can be flagged as such
+	var pending *Node
+	var pendingDir *dirState
+	for entries != nil {
+		if w.stopped() {
+			break
+		}
+		entry, ok := entries.next()
+		if !ok {
+			break
+		}
+		child, childDir := w.newEntry(*sub, entry)
+		if child == nil {
+			continue
+		}
+		if !w.takeEntry() {
+			break
+		}
+		child.Parent = node
+		countNode(dirStats, child)
+		w.options.Progress.addEntry(child)
+
+		if pending != nil {
+			if err := w.streamNode(pending, pendingDir, depth+1, false, visitor); err != nil {
+				return err
+			}
+		}
+		pending, pendingDir = child, childDir
+	}
+	if pending != nil {
+		if err := w.streamNode(pending, pendingDir, depth+1, true, visitor); err != nil {
+			return err
+		}
+	}
+
+	// The node has been seen already, so only the visitor's Leave and
+	// the error count can tell that the rest of the directory is missing
+	if entries != nil && entries.err != nil {
+		node.Err = newWalkError("reading dir", entries.err)
+		countNode(dirStats, node)
+	}
+	if dirStats != nil {
+		w.options.Stats.Merge(dirStats)
+	}
+
+	if depth == 0 {
+		w.finish(node)
+	}
+	return visitor.Leave(node, depth)
+}
+
+// dirStream reads the entries of a directory a batch at a time, in the
+// order the file system returns them
+type dirStream struct {
+	file    fs.ReadDirFile
+	entries []fs.DirEntry // read but not yet returned
+	err     error         // why reading stopped before the end, if it did
+	done    bool          // no batches are left to read
+}
+
+// openDirectory starts reading dir for streamNode. The first batch is
+// read right away, so that a directory that cannot be read is marked
+// before it is visited. It returns nil if there is nothing to list.
+func (w *walker) openDirectory(dir dirState) *dirStream {
+	if !w.startDirectory(dir) {
+		return nil
+	}
+
+	file, err := w.fsys.Open(dir.path)
+	if err != nil {
+		w.readFailed(dir, err)
+		return nil
+	}
+	dirFile, ok := file.(fs.ReadDirFile)
+	if !ok {
+		file.Close()
+		w.readFailed(dir, &fs.PathError{Op: "readdir", Path: dir.path, Err: errors.ErrUnsupported})
+		return nil
+	}
+
+	d := &dirStream{file: dirFile}
+	d.fill()
+	if w.options.FileLimit > 0 && dir.depth > 0 {
+		for !d.done && len(d.entries) <= w.options.FileLimit {
+			d.fill()
+		}
+	}
+	if d.err != nil && len(d.entries) == 0 {
+		d.file.Close()
+		w.readFailed(dir, d.err)
+		return nil
+	}
+
+	// Summarize crowded directories, counting the rest a batch at a time
+	if w.overFileLimit(dir, len(d.entries)) {
+		addUnlisted(dir.node, d.entries)
+		for !d.done {
+			d.entries = nil
+			d.fill()
+			addUnlisted(dir.node, d.entries)
+		}
+		d.file.Close()
+		return nil
+	}
+	return d
+}
+
+// fill appends the next batch of entries, if there is one
+func (d *dirStream) fill() {
+	if d.done {
+		return
+	}
+	batch, err := d.file.ReadDir(streamBatch)
+	d.entries = append(d.entries, batch...)
+	if err != nil {
+		d.done = true
+		if err != io.EOF {
+			d.err = err
+		}
+	}
+}
+
+// next returns the next entry, or false once none are left
+func (d *dirStream) next() (fs.DirEntry, bool) {
+	if len(d.entries) == 0 {
+		d.entries = nil
+		d.fill()
+		if len(d.entries) == 0 {
+			return nil, false
+		}
+	}
+	entry := d.entries[0]
+	d.entries = d.entries[1:]
+	return entry, true
+}
@@ -0,0 +1,22 @@
+//go:build !unix
+
+package tree
+
+import "os"
+
+// fileID uniquely identifies a file on the system
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// getFileID is not supported on this platform
+func getFileID(info os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
+
+// fillSysInfo falls back to the apparent size, as allocation details are
+// not available on this platform
+func fillSysInfo(node *Node, info os.FileInfo) {
+	node.DiskSize = info.Size()
+}
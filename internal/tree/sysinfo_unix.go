@@ -0,0 +1,39 @@
+//go:build unix
+
+package tree
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID uniquely identifies a file on the system
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// getFileID extracts the device and inode numbers from file info
+func getFileID(info os.FileInfo) (fileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+}
+
+// fillSysInfo copies the platform-specific details of info into node
+func fillSysInfo(node *Node, info os.FileInfo) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	node.Device = uint64(stat.Dev)
+	node.Inode = uint64(stat.Ino)
+	node.Links = uint64(stat.Nlink)
+	node.UID = stat.Uid
+	node.GID = stat.Gid
+	node.hasIDs = true
+	// st_blocks is always counted in 512-byte units
+	node.DiskSize = int64(stat.Blocks) * 512
+}
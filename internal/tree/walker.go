@@ -1,110 +1,489 @@
 package tree
 
 import (
+	"context"
+	"errors"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"dtree/internal/glob"
+	"dtree/internal/stats"
 )
 
 // WalkerOptions contains options for directory walking
 type WalkerOptions struct {
 	ShowHidden bool
 	MaxDepth   int
-	RootPath   string
+	RootPath   string     // where the walked filesystem lives; used to name the root and fill Node.Path
+	GitIgnore  *GitIgnore // nil disables .gitignore handling
+
+	Patterns       []*glob.Pattern // only files matching one of these are listed
+	IgnorePatterns []*glob.Pattern // entries matching one of these are skipped
+	Prune          bool            // drop directories left without any entries
+
+	// Filter, if set, keeps only matching entries and the directories
+	// leading to them. It runs after the walk, so it can inspect children.
+	Filter func(*Node) bool
+
+	FollowSymlinks bool // descend into symlinked directories
+	Archives       bool // list the contents of zip, jar and tar files
+	Detect         bool // sniff file contents for a MIME type and kind
+	OneFileSystem  bool // do not cross into directories on other devices
+
+	FileLimit  int // do not descend into directories with more entries than this (0 = no limit)
+	MaxEntries int // stop the walk after this many entries (0 = no limit)
+
+	Jobs     int              // directories read in parallel (0 = number of CPUs)
+	Stats    *stats.FileStats // receives totals gathered during the walk, if set
+	Progress *Progress        // receives live counters during the walk, if set
 }
 
-// WalkTree builds a tree structure from the given root path
-func WalkTree(rootPath string, options WalkerOptions) (*Node, error) {
-	absPath, err := filepath.Abs(rootPath)
-	if err != nil {
-		return nil, err
+// WalkError records why an entry could not be read during the walk
+type WalkError struct {
+	Op  string // what was being attempted, e.g. "opening dir"
+	Err error  // underlying cause, without the path
+}
+
+func (e *WalkError) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *WalkError) Unwrap() error {
+	return e.Err
+}
+
+// newWalkError wraps err, dropping the path already shown by the tree
+func newWalkError(op string, err error) *WalkError {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
 	}
+	return &WalkError{Op: op, Err: err}
+}
 
-	info, err := os.Stat(absPath)
+// walker holds the state shared by all goroutines of a single walk
+type walker struct {
+	ctx     context.Context
+	fsys    fs.FS
+	options WalkerOptions
+	slots   chan struct{} // one token per extra goroutine allowed
+	wg      sync.WaitGroup
+
+	rootID   fileID // identity of the walk root
+	rootIDOK bool   // whether rootID is known
+
+	remaining atomic.Int64 // entries left under MaxEntries
+	truncated atomic.Bool  // MaxEntries was reached
+	cancelled atomic.Bool  // the context ended before the walk did
+}
+
+// dirState describes a directory waiting to be read
+type dirState struct {
+	node      *Node
+	path      string // slash-separated path within the walked filesystem ("." for the root)
+	ignore    *GitIgnore
+	ancestors *dirChain
+	depth     int
+}
+
+// WalkTree builds a tree structure from the root of fsys.
+// Subdirectories are read concurrently, but children always appear in the
+// same order as a serial walk would produce.
+//
+// If ctx ends before the walk is complete, WalkTree stops reading and
+// returns what it has collected so far, with Incomplete set on the root.
+//
+// Symlinks, loop detection and device checks rely on fs.Stat following
+// links and on FileInfo.Sys returning a syscall.Stat_t, as os.DirFS does;
+// other filesystems simply report no links and no devices.
+func WalkTree(ctx context.Context, fsys fs.FS, options WalkerOptions) (*Node, error) {
+	root, w, err := newWalker(ctx, fsys, options)
 	if err != nil {
 		return nil, err
 	}
 
-	root := NewNode(filepath.Dir(absPath), info)
-	root.Name = filepath.Base(absPath)
-	root.Path = filepath.Dir(absPath)
+	w.walkDirectory(w.rootState(root))
+	w.wg.Wait()
+	w.finish(root)
+
+	finishTree(root, options)
+	return root, nil
+}
+
+// finishTree applies the options that need the complete tree
+func finishTree(root *Node, options WalkerOptions) {
+	if options.Filter != nil {
+		// The root always stays, even when nothing matches
+		filterTree(root, options.Filter)
+	}
+	if options.Prune {
+		pruneEmptyDirs(root)
+	}
+}
 
-	err = walkDirectory(root, absPath, options, 0)
+// WalkPath builds a tree structure from a directory on disk
+func WalkPath(ctx context.Context, rootPath string, options WalkerOptions) (*Node, error) {
+	absPath, err := filepath.Abs(rootPath)
 	if err != nil {
 		return nil, err
 	}
+	options.RootPath = absPath
+	return WalkTree(ctx, os.DirFS(absPath), options)
+}
 
-	return root, nil
+// newWalker stats the root of fsys and prepares the shared walk state
+func newWalker(ctx context.Context, fsys fs.FS, options WalkerOptions) (*Node, *walker, error) {
+	info, err := fs.Stat(fsys, ".")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := NewNode("", info)
+	if options.RootPath != "" {
+		root.Name = filepath.Base(options.RootPath)
+		root.Path = filepath.Dir(options.RootPath)
+	} else {
+		root.Name = "."
+	}
+
+	jobs := options.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	if options.MaxEntries > 0 {
+		// A global budget is only deterministic when spent in walk order
+		jobs = 1
+	}
+	w := &walker{
+		ctx:     ctx,
+		fsys:    fsys,
+		options: options,
+		slots:   make(chan struct{}, jobs-1),
+	}
+	w.rootID, w.rootIDOK = getFileID(info)
+	w.remaining.Store(int64(options.MaxEntries))
+
+	return root, w, nil
+}
+
+// finish records on the root why the walk ended early, if it did
+func (w *walker) finish(root *Node) {
+	root.Truncated = w.truncated.Load()
+	root.Incomplete = w.cancelled.Load()
+}
+
+// stopped reports whether the walk should stop reading, because the
+// context ended or the entry budget ran out
+func (w *walker) stopped() bool {
+	if w.ctx.Err() != nil {
+		w.cancelled.Store(true)
+		return true
+	}
+	return w.truncated.Load()
+}
+
+// displayPath converts a path within the filesystem into the form stored
+// in Node.Path
+func (w *walker) displayPath(fsPath string) string {
+	if w.options.RootPath == "" {
+		return fsPath
+	}
+	return filepath.Join(w.options.RootPath, filepath.FromSlash(fsPath))
+}
+
+// rootState describes the walk root as the first directory to read
+func (w *walker) rootState(root *Node) dirState {
+	var ancestors *dirChain
+	return dirState{
+		node:      root,
+		path:      ".",
+		ignore:    w.options.GitIgnore,
+		ancestors: ancestors.push(w.rootID, w.rootIDOK),
+	}
+}
+
+// walkDirectory reads one directory and descends into its subdirectories,
+// handing them to another goroutine whenever a slot is free
+func (w *walker) walkDirectory(dir dirState) {
+	subdirs := w.readDirectory(dir)
+
+	for _, sub := range subdirs {
+		select {
+		case w.slots <- struct{}{}:
+			w.wg.Add(1)
+			go func(sub dirState) {
+				defer func() {
+					<-w.slots
+					w.wg.Done()
+				}()
+				w.walkDirectory(sub)
+			}(sub)
+		default:
+			w.walkDirectory(sub)
+		}
+	}
+}
+
+// readDirectory adds the entries of dir to its node and returns the
+// subdirectories that should be walked next
+func (w *walker) readDirectory(dir dirState) []dirState {
+	entries := w.listDirectory(dir)
+
+	var subdirs []dirState
+	var dirStats *stats.FileStats
+	if w.options.Stats != nil {
+		dirStats = stats.NewStats()
+	}
+
+	for _, entry := range entries {
+		if w.stopped() {
+			break
+		}
+		node, sub := w.newEntry(dir, entry)
+		if node == nil {
+			continue
+		}
+		if !w.takeEntry() {
+			break
+		}
+		dir.node.AddChild(node)
+		countNode(dirStats, node)
+		w.options.Progress.addEntry(node)
+		if sub != nil {
+			subdirs = append(subdirs, *sub)
+		}
+	}
+
+	if dirStats != nil {
+		w.options.Stats.Merge(dirStats)
+	}
+
+	return subdirs
 }
 
-func walkDirectory(parent *Node, dirPath string, options WalkerOptions, currentDepth int) error {
-	// Check depth limit
-	if options.MaxDepth > 0 && currentDepth >= options.MaxDepth {
+// listDirectory returns the entries of dir, or nil if the depth limit is
+// reached or the directory cannot be read
+func (w *walker) listDirectory(dir dirState) []fs.DirEntry {
+	if !w.startDirectory(dir) {
 		return nil
 	}
 
-	entries, err := os.ReadDir(dirPath)
+	entries, err := fs.ReadDir(w.fsys, dir.path)
 	if err != nil {
-		// Skip directories we can't read (permission denied, etc.)
+		w.readFailed(dir, err)
 		return nil
 	}
 
+	// Summarize crowded directories instead of listing them
+	if w.overFileLimit(dir, len(entries)) {
+		addUnlisted(dir.node, entries)
+		return nil
+	}
+
+	return entries
+}
+
+// startDirectory reports whether the entries of dir should be read. It
+// marks dir when the depth limit is reached.
+func (w *walker) startDirectory(dir dirState) bool {
+	if w.options.MaxDepth > 0 && dir.depth >= w.options.MaxDepth {
+		dir.node.DepthLimited = true
+		return false
+	}
+	if w.stopped() {
+		return false
+	}
+	w.options.Progress.enterDir(w.displayPath(dir.path))
+	return true
+}
+
+// readFailed keeps a directory that could not be read in the tree, but
+// remembers why it is empty
+func (w *walker) readFailed(dir dirState, err error) {
+	dir.node.Err = newWalkError("opening dir", err)
+	if w.options.Stats != nil {
+		w.options.Stats.Merge(&stats.FileStats{Errors: 1})
+	}
+}
+
+// overFileLimit reports whether dir holds too many entries to be listed
+func (w *walker) overFileLimit(dir dirState, count int) bool {
+	limit := w.options.FileLimit
+	return limit > 0 && dir.depth > 0 && count > limit
+}
+
+// addUnlisted counts entries of node that are left out because of the
+// file limit, along with the size of the files among them
+func addUnlisted(node *Node, entries []fs.DirEntry) {
+	node.EntryCount += len(entries)
 	for _, entry := range entries {
-		// Skip hidden files if not showing them
-		if !options.ShowHidden && entry.Name()[0] == '.' {
-			continue
+		if info, err := entry.Info(); err == nil && !info.IsDir() {
+			node.EntryBytes += info.Size()
 		}
+	}
+}
 
-		info, err := entry.Info()
-		if err != nil {
-			// Skip files we can't stat
-			continue
+// takeEntry spends one entry of the MaxEntries budget. Once the budget is
+// exhausted it marks the root as truncated and returns false.
+func (w *walker) takeEntry() bool {
+	if w.options.MaxEntries <= 0 {
+		return true
+	}
+	if w.remaining.Add(-1) < 0 {
+		w.truncated.Store(true)
+		return false
+	}
+	return true
+}
+
+// newEntry builds the node for one directory entry. It returns a nil node
+// if the entry is filtered out, and a non-nil dirState if the walk should
+// descend into it. The node is not attached to dir.node.
+func (w *walker) newEntry(dir dirState, entry fs.DirEntry) (*Node, *dirState) {
+	options := w.options
+
+	// Skip hidden files if not showing them
+	if !options.ShowHidden && entry.Name()[0] == '.' {
+		return nil, nil
+	}
+
+	relPath := path.Join(dir.path, entry.Name())
+	parentPath := w.displayPath(dir.path)
+
+	// Skip the repository itself and anything matched by ignore rules
+	if dir.ignore != nil {
+		if entry.Name() == ".git" || dir.ignore.Ignored(relPath, entry.IsDir()) {
+			return nil, nil
 		}
+	}
+
+	info, err := entry.Info()
+	if err != nil {
+		// Show the entry anyway so the failure is visible
+		return &Node{
+			Name:     entry.Name(),
+			Path:     parentPath,
+			IsDir:    entry.IsDir(),
+			Mode:     entry.Type(),
+			Children: make([]*Node, 0),
+			Err:      newWalkError("reading file info", err),
+		}, nil
+	}
 
-		fullPath := filepath.Join(dirPath, entry.Name())
-		node := NewNode(dirPath, info)
-
-		// Handle symlinks
-		if entry.Type()&os.ModeSymlink != 0 {
-			node.IsSymlink = true
-			// Try to resolve symlink target
-			resolved, err := filepath.EvalSymlinks(fullPath)
-			if err == nil {
-				info, err := os.Stat(resolved)
-				if err == nil {
-					node.IsDir = info.IsDir()
-					node.Size = info.Size()
-					node.ModTime = info.ModTime()
-				}
-			} else {
-				// Symlink target doesn't exist, keep original info
-				node.IsDir = false
-			}
+	node := NewNode(parentPath, info)
+
+	// Handle symlinks
+	if entry.Type()&fs.ModeSymlink != 0 {
+		node.IsSymlink = true
+		// Describe the target; broken links keep the link's own info
+		target, err := fs.Stat(w.fsys, relPath)
+		if err == nil {
+			node.IsDir = target.IsDir()
+			node.Size = target.Size()
+			node.ModTime = target.ModTime()
+			node.linkMode = target.Mode()
+			fillSysInfo(node, target)
+			info = target
+		} else {
+			node.IsDir = false
+			node.IsBroken = true
 		}
+	}
 
-		parent.AddChild(node)
-
-		// Recursively walk subdirectories
-		if node.IsDir && !node.IsSymlink {
-			err := walkDirectory(node, fullPath, options, currentDepth+1)
-			if err != nil {
-				// Continue even if subdirectory can't be read
-				continue
-			}
-		} else if node.IsDir && node.IsSymlink {
-			// Follow symlink if it points to a directory
-			resolved, err := filepath.EvalSymlinks(fullPath)
-			if err == nil {
-				info, err := os.Stat(resolved)
-				if err == nil && info.IsDir() {
-					err := walkDirectory(node, resolved, options, currentDepth+1)
-					if err != nil {
-						continue
-					}
-				}
-			}
+	// Apply include/ignore patterns. Archives that will be listed hold
+	// members like a directory, so include patterns apply to those instead.
+	isArchive := !node.IsDir && options.Archives && archiveFormat(node.Name) != ""
+	if excluded(options, relPath, node.IsDir || isArchive) {
+		return nil, nil
+	}
+
+	// Sniff the contents of files, including the targets of links
+	if options.Detect && info.Mode().IsRegular() {
+		w.detectType(node, relPath)
+	}
+
+	if isArchive {
+		node.IsArchive = true
+		// Members count towards the depth limit like directories
+		levels := 0
+		if options.MaxDepth > 0 {
+			levels = options.MaxDepth - (dir.depth + 1)
 		}
+		if options.MaxDepth == 0 || levels > 0 {
+			w.expandArchive(node, relPath, archiveFormat(node.Name), levels)
+		} else {
+			node.DepthLimited = true
+		}
+	}
+
+	// Symlinked directories are only expanded when following is enabled
+	if !node.IsDir || (node.IsSymlink && !options.FollowSymlinks) {
+		return node, nil
+	}
+
+	// Never descend into a directory we are already inside of
+	id, ok := getFileID(info)
+	if ok && dir.ancestors.contains(id) {
+		node.IsLoop = true
+		return node, nil
+	}
+
+	// Stop at mount points when staying on one filesystem
+	if options.OneFileSystem && ok && w.rootIDOK && id.dev != w.rootID.dev {
+		node.IsMountPoint = true
+		return node, nil
+	}
+
+	return node, &dirState{
+		node:      node,
+		path:      relPath,
+		ignore:    dir.ignore.Enter(w.fsys, relPath),
+		ancestors: dir.ancestors.push(id, ok),
+		depth:     dir.depth + 1,
+	}
+}
+
+// countNode adds a node to the walk statistics, if they are collected
+func countNode(s *stats.FileStats, node *Node) {
+	if s == nil {
+		return
 	}
+	switch {
+	case node.Err != nil:
+		s.AddError()
+	case node.IsDir:
+		s.AddDir()
+	default:
+		s.AddFile(node.Size, node.ModTime)
+	}
+}
+
+// dirChain is the list of directories between the root and the directory
+// being walked, used to detect symlink loops
+type dirChain struct {
+	id     fileID
+	parent *dirChain
+}
 
-	return nil
+// push returns a chain extended with id, or the chain unchanged if the
+// directory could not be identified
+func (c *dirChain) push(id fileID, ok bool) *dirChain {
+	if !ok {
+		return c
+	}
+	return &dirChain{id: id, parent: c}
 }
 
+func (c *dirChain) contains(id fileID) bool {
+	for ; c != nil; c = c.parent {
+		if c.id == id {
+			return true
+		}
+	}
+	return false
+}